@@ -0,0 +1,139 @@
+package sgd
+
+import (
+	"errors"
+	"io"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// Accumulator averages gradients over several consecutive
+// mini-batches (micro-batches), making it possible to
+// emulate a large batch size when only small batches fit
+// in memory.
+//
+// Until Steps gradients have been accumulated, Gradient
+// and Transform return nil.
+// The training loops in this package treat a nil gradient
+// as "no update yet": the parameters are left alone and
+// the step counter (and thus the Schedule) does not
+// advance.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type Accumulator struct {
+	Gradienter Gradienter
+
+	// Transformer, if non-nil, is applied to each averaged
+	// gradient before it is returned.
+	//
+	// This is equivalent to wrapping the Accumulator in
+	// the Transformer, since Transformers ignore nil
+	// gradients, so either way the Transformer's state
+	// only advances once per virtual batch.
+	Transformer Transformer
+
+	// Steps is the number of micro-batches in each
+	// virtual batch.
+	// If it is 0, a value of 1 is used.
+	Steps int
+
+	sum   autofunc.Gradient
+	count int
+}
+
+func (a *Accumulator) Gradient(s SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+// Transform adds grad to the running sum.
+// If this completes a virtual batch, the average of the
+// accumulated gradients is passed through Transformer and
+// returned.
+// Otherwise, nil is returned.
+//
+// A nil grad is ignored, and does not count towards the
+// virtual batch.
+// A variable which is missing from some of the
+// micro-batches' gradients is treated as having a zero
+// gradient in those micro-batches.
+func (a *Accumulator) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	if a.count == 0 {
+		a.sum = grad.Copy()
+	} else {
+		for variable, vec := range grad {
+			if sumVec, ok := a.sum[variable]; ok {
+				for i, x := range vec {
+					sumVec[i] += x
+				}
+			} else {
+				a.sum[variable] = vec.Copy()
+			}
+		}
+	}
+	a.count++
+
+	steps := a.steps()
+	if a.count < steps {
+		return nil
+	}
+	a.count = 0
+	a.sum.Scale(1 / float64(steps))
+	if a.Transformer != nil {
+		return a.Transformer.Transform(a.sum)
+	}
+	return a.sum
+}
+
+func (a *Accumulator) steps() int {
+	if a.Steps == 0 {
+		return 1
+	}
+	return a.Steps
+}
+
+// SaveState saves the partially accumulated gradient.
+//
+// The state of the Transformer is not included, and
+// should be saved separately.
+func (a *Accumulator) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	if err := writeStateHeader(w, "Accumulator"); err != nil {
+		return err
+	}
+	if err := writeStateInt(w, a.count); err != nil {
+		return err
+	}
+	var sum autofunc.Gradient
+	if a.count > 0 {
+		sum = a.sum
+	}
+	return writeStateGradient(w, sum, params)
+}
+
+// LoadState loads state saved with SaveState.
+//
+// The saved number of micro-batches must be less than
+// Steps.
+func (a *Accumulator) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	if err := readStateHeader(r, "Accumulator"); err != nil {
+		return err
+	}
+	count, err := readStateInt(r)
+	if err != nil {
+		return err
+	}
+	sum, err := readStateGradient(r, params)
+	if err != nil {
+		return err
+	}
+	if count < 0 || count >= a.steps() || (count > 0) != (sum != nil) {
+		return errors.New("invalid accumulator state")
+	}
+	a.count = count
+	a.sum = sum
+	return nil
+}
@@ -0,0 +1,127 @@
+package sgd
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+func TestAccumulatorWrapped(t *testing.T) {
+	samples := SliceSampleSet{}
+	for i := 0; i < 9; i++ {
+		samples = append(samples, float64(i))
+	}
+	var results [2][]float64
+	for i := range results {
+		g := newTargetTestGradienter()
+		var adam *Adam
+		var gradienter Gradienter
+		if i == 0 {
+			adam = &Adam{Gradienter: &Accumulator{Gradienter: g, Steps: 3}}
+			gradienter = adam
+		} else {
+			adam = &Adam{}
+			gradienter = &Accumulator{Gradienter: g, Transformer: adam, Steps: 3}
+		}
+		trainer := &Trainer{
+			Gradienter: gradienter,
+			Samples:    samples,
+			BatchSize:  1,
+			Schedule:   ConstantSchedule(0.1),
+			Budget:     Budget{Epochs: 2},
+			Rand:       NewRandSource(1),
+		}
+		if err := trainer.Run(context.Background()); err != ErrEpochLimit {
+			t.Fatal(err)
+		}
+		if trainer.Step != 6 {
+			t.Errorf("wiring %d: expected 6 steps but got %d", i, trainer.Step)
+		}
+		if adam.iteration != 6 {
+			t.Errorf("wiring %d: expected 6 Adam iterations but got %v", i, adam.iteration)
+		}
+		for _, v := range g.Vars {
+			results[i] = append(results[i], v.Vector...)
+		}
+	}
+	for i, x := range results[0] {
+		if math.Abs(x-results[1][i]) > 1e-8 {
+			t.Errorf("wirings differ: %v vs %v", results[0], results[1])
+			break
+		}
+	}
+}
+
+func TestAccumulatorMean(t *testing.T) {
+	v := &autofunc.Variable{Vector: []float64{1, -2, 3}}
+	g := &weightedTestGradienter{Var: v}
+	a := &Accumulator{Gradienter: g, Steps: 3}
+	for cycle := 0; cycle < 2; cycle++ {
+		batches := []SampleSet{SliceSampleSet{1.0}, SliceSampleSet{2.0, 3.0}, SliceSampleSet{4.0}}
+		expected := autofunc.NewGradient([]*autofunc.Variable{v})
+		for i, batch := range batches {
+			expected.Add(g.Gradient(batch))
+			res := a.Gradient(batch)
+			if i < len(batches)-1 {
+				if res != nil {
+					t.Fatalf("cycle %d: expected nil after micro-batch %d", cycle, i)
+				}
+				continue
+			}
+			expected.Scale(1.0 / 3)
+			if !accumulatorGradientsClose(expected, res) {
+				t.Errorf("cycle %d: expected %v but got %v", cycle, expected, res)
+			}
+		}
+	}
+}
+
+func TestAccumulatorChangingVariables(t *testing.T) {
+	v1 := &autofunc.Variable{Vector: []float64{1, 2}}
+	v2 := &autofunc.Variable{Vector: []float64{3}}
+	a := &Accumulator{Steps: 2}
+	a.Transform(autofunc.Gradient{v1: []float64{2, 4}, v2: []float64{6}})
+	a.Transform(autofunc.Gradient{v1: []float64{2, 2}})
+	a.Transform(autofunc.Gradient{v1: []float64{4, 4}})
+	res := a.Transform(autofunc.Gradient{v2: []float64{2}})
+	expected := autofunc.Gradient{v1: []float64{2, 2}, v2: []float64{1}}
+	if !accumulatorGradientsClose(expected, res) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+}
+
+func TestAccumulatorInvalidState(t *testing.T) {
+	v := &autofunc.Variable{Vector: []float64{1}}
+	params := []*autofunc.Variable{v}
+	for _, count := range []int{-1, 2, 3} {
+		var buf bytes.Buffer
+		saved := &Accumulator{Steps: 3, count: count, sum: autofunc.Gradient{v: []float64{1}}}
+		if err := saved.SaveState(&buf, params); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&Accumulator{Steps: 2}).LoadState(&buf, params); err == nil {
+			t.Errorf("count %d: expected an error", count)
+		}
+	}
+}
+
+func accumulatorGradientsClose(expected, actual autofunc.Gradient) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for variable, vec := range expected {
+		actualVec, ok := actual[variable]
+		if !ok || len(actualVec) != len(vec) {
+			return false
+		}
+		for i, x := range vec {
+			if math.Abs(actualVec[i]-x) > 1e-8 {
+				return false
+			}
+		}
+	}
+	return true
+}
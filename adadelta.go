@@ -0,0 +1,104 @@
+package sgd
+
+import (
+	"io"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+)
+
+const (
+	adadeltaDefaultDecay   = 0.95
+	adadeltaDefaultDamping = 1e-6
+)
+
+// Adadelta is a Gradienter and a Transformer which
+// implements the Adadelta algorithm described in
+// https://arxiv.org/abs/1212.5701.
+//
+// Adadelta scales each gradient entry by the ratio of the
+// root mean square of recent updates to the root mean
+// square of recent gradients, so the updates have the
+// same units as the parameters and no global step size
+// needs to be tuned.
+// For the original algorithm, use a step size of 1.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type Adadelta struct {
+	Gradienter Gradienter
+
+	// Decay is the decay rate for the running averages
+	// of squared gradients and squared updates.
+	// If it is 0, a default of 0.95 is used.
+	Decay float64
+
+	// Damping is added to both running averages before
+	// computing their square roots.
+	// Besides preventing divisions by zero, it determines
+	// the size of the first few updates.
+	// If it is 0, a default of 1e-6 is used.
+	Damping float64
+
+	squaredGrads   autofunc.Gradient
+	squaredUpdates autofunc.Gradient
+}
+
+func (a *Adadelta) Gradient(s SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+func (a *Adadelta) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	decay := defaultFloat(a.Decay, adadeltaDefaultDecay)
+	damping := defaultFloat(a.Damping, adadeltaDefaultDamping)
+	if a.squaredGrads == nil {
+		a.squaredGrads = grad.Copy()
+		a.squaredGrads.Zero()
+		a.squaredUpdates = grad.Copy()
+		a.squaredUpdates.Zero()
+	}
+	for variable, vec := range grad {
+		gradVec := a.squaredGrads[variable]
+		updateVec := a.squaredUpdates[variable]
+		for i, x := range vec {
+			gradVec[i] = decay*gradVec[i] + (1-decay)*x*x
+			update := x * math.Sqrt(updateVec[i]+damping) / math.Sqrt(gradVec[i]+damping)
+			updateVec[i] = decay*updateVec[i] + (1-decay)*update*update
+			vec[i] = update
+		}
+	}
+	return grad
+}
+
+// SaveState saves the running averages.
+func (a *Adadelta) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	if err := writeStateHeader(w, "Adadelta"); err != nil {
+		return err
+	}
+	if err := writeStateGradient(w, a.squaredGrads, params); err != nil {
+		return err
+	}
+	return writeStateGradient(w, a.squaredUpdates, params)
+}
+
+// LoadState loads state saved with SaveState.
+func (a *Adadelta) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	if err := readStateHeader(r, "Adadelta"); err != nil {
+		return err
+	}
+	grads, err := readStateGradient(r, params)
+	if err != nil {
+		return err
+	}
+	updates, err := readStateGradient(r, params)
+	if err != nil {
+		return err
+	}
+	a.squaredGrads = grads
+	a.squaredUpdates = updates
+	return nil
+}
@@ -0,0 +1,63 @@
+package sgd
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestAdadeltaConvergence(t *testing.T) {
+	q := newQuadraticTestGradienter()
+	q.Scales = []float64{1, 2, 4}
+	a := &Adadelta{Gradienter: q}
+	initCost := q.Cost(nil)
+	if cost := runQuadraticTest(q, a, 500, 1); cost > initCost/10 {
+		t.Errorf("cost went from %f to %f", initCost, cost)
+	}
+}
+
+func TestAdadeltaScaleInvariance(t *testing.T) {
+	q := newQuadraticTestGradienter()
+	a1 := &Adadelta{}
+	a2 := &Adadelta{}
+	for i := 0; i < 10; i++ {
+		grad := q.Gradient(nil)
+		scaled := grad.Copy()
+		scaled.Scale(1000)
+		update1 := a1.Transform(grad)[q.Var]
+		update2 := a2.Transform(scaled)[q.Var]
+		for j, x := range update1 {
+			if math.Abs(x-update2[j]) > math.Abs(x)*1e-3 {
+				t.Fatalf("step %d: updates %v and %v differ", i, update1, update2)
+			}
+		}
+		for j, x := range update1 {
+			q.Var.Vector[j] -= x
+		}
+	}
+}
+
+func TestAdadeltaState(t *testing.T) {
+	q1 := newQuadraticTestGradienter()
+	a1 := &Adadelta{Gradienter: q1}
+	runQuadraticTest(q1, a1, 10, 1)
+
+	var buf bytes.Buffer
+	if err := a1.SaveState(&buf, q1.Parameters()); err != nil {
+		t.Fatal(err)
+	}
+	q2 := newQuadraticTestGradienter()
+	copy(q2.Var.Vector, q1.Var.Vector)
+	a2 := &Adadelta{Gradienter: q2}
+	if err := a2.LoadState(&buf, q2.Parameters()); err != nil {
+		t.Fatal(err)
+	}
+
+	runQuadraticTest(q1, a1, 10, 1)
+	runQuadraticTest(q2, a2, 10, 1)
+	for i, x := range q1.Var.Vector {
+		if q2.Var.Vector[i] != x {
+			t.Errorf("entry %d: expected %f but got %f", i, x, q2.Var.Vector[i])
+		}
+	}
+}
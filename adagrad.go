@@ -1,6 +1,7 @@
 package sgd
 
 import (
+	"io"
 	"math"
 
 	"github.com/unixpickle/autofunc"
@@ -24,6 +25,9 @@ func (a *AdaGrad) Gradient(s SampleSet) autofunc.Gradient {
 }
 
 func (a *AdaGrad) Transform(actualGrad autofunc.Gradient) autofunc.Gradient {
+	if actualGrad == nil {
+		return nil
+	}
 	if a.squaredHistory == nil {
 		a.squaredHistory = actualGrad.Copy()
 		for _, v := range a.squaredHistory {
@@ -49,3 +53,24 @@ func (a *AdaGrad) Transform(actualGrad autofunc.Gradient) autofunc.Gradient {
 
 	return actualGrad
 }
+
+// SaveState saves the accumulated squared gradients.
+func (a *AdaGrad) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	if err := writeStateHeader(w, "AdaGrad"); err != nil {
+		return err
+	}
+	return writeStateGradient(w, a.squaredHistory, params)
+}
+
+// LoadState loads state saved with SaveState.
+func (a *AdaGrad) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	if err := readStateHeader(r, "AdaGrad"); err != nil {
+		return err
+	}
+	hist, err := readStateGradient(r, params)
+	if err != nil {
+		return err
+	}
+	a.squaredHistory = hist
+	return nil
+}
@@ -1,6 +1,7 @@
 package sgd
 
 import (
+	"io"
 	"math"
 
 	"github.com/unixpickle/autofunc"
@@ -42,6 +43,9 @@ func (a *Adam) Gradient(s SampleSet) autofunc.Gradient {
 }
 
 func (a *Adam) Transform(realGradient autofunc.Gradient) autofunc.Gradient {
+	if realGradient == nil {
+		return nil
+	}
 	a.updateMoments(realGradient)
 
 	a.iteration++
@@ -59,7 +63,70 @@ func (a *Adam) Transform(realGradient autofunc.Gradient) autofunc.Gradient {
 	return realGradient
 }
 
+// SaveState saves the moment estimates and iteration
+// count.
+func (a *Adam) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return a.saveMoments(w, "Adam", params)
+}
+
+// LoadState loads state saved with SaveState.
+func (a *Adam) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return a.loadMoments(r, "Adam", params)
+}
+
+// saveMoments saves the iteration count and moments,
+// followed by any extra per-parameter state used by a
+// variant of Adam.
+func (a *Adam) saveMoments(w io.Writer, name string, params []*autofunc.Variable,
+	extra ...autofunc.Gradient) error {
+	if err := writeStateHeader(w, name); err != nil {
+		return err
+	}
+	if err := writeStateFloat(w, a.iteration); err != nil {
+		return err
+	}
+	grads := append([]autofunc.Gradient{a.firstMoment, a.secondMoment}, extra...)
+	for _, g := range grads {
+		if err := writeStateGradient(w, g, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMoments loads state saved with saveMoments.
+// The receiver is only modified if the entire state is
+// read successfully.
+func (a *Adam) loadMoments(r io.Reader, name string, params []*autofunc.Variable,
+	extra ...*autofunc.Gradient) error {
+	if err := readStateHeader(r, name); err != nil {
+		return err
+	}
+	iteration, err := readStateFloat(r)
+	if err != nil {
+		return err
+	}
+	dests := append([]*autofunc.Gradient{&a.firstMoment, &a.secondMoment}, extra...)
+	grads := make([]autofunc.Gradient, len(dests))
+	for i := range grads {
+		grads[i], err = readStateGradient(r, params)
+		if err != nil {
+			return err
+		}
+	}
+	a.iteration = iteration
+	for i, dest := range dests {
+		*dest = grads[i]
+	}
+	return nil
+}
+
 func (a *Adam) updateMoments(grad autofunc.Gradient) {
+	a.updateFirstMoment(grad)
+	a.updateSecondMoment(grad)
+}
+
+func (a *Adam) updateFirstMoment(grad autofunc.Gradient) {
 	if a.firstMoment == nil {
 		a.firstMoment = grad.Copy()
 		a.firstMoment.Scale(1 - a.decayRate(1))
@@ -75,6 +142,9 @@ func (a *Adam) updateMoments(grad autofunc.Gradient) {
 			}
 		}
 	}
+}
+
+func (a *Adam) updateSecondMoment(grad autofunc.Gradient) {
 	if a.secondMoment == nil {
 		a.secondMoment = grad.Copy()
 		for _, v := range a.secondMoment {
@@ -0,0 +1,88 @@
+package sgd
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+type zeroTestGradienter []*autofunc.Variable
+
+func (z zeroTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	return autofunc.NewGradient(z)
+}
+
+func TestAdamWDecay(t *testing.T) {
+	weights := &autofunc.Variable{Vector: []float64{2, -4}}
+	biases := &autofunc.Variable{Vector: []float64{3}}
+	a := &AdamW{
+		Adam:        Adam{Gradienter: zeroTestGradienter{weights, biases}},
+		WeightDecay: 0.5,
+		NoDecay:     map[*autofunc.Variable]bool{biases: true},
+	}
+	grad := a.Gradient(nil)
+	expected := map[*autofunc.Variable][]float64{
+		weights: {1, -2},
+		biases:  {0},
+	}
+	for variable, vec := range expected {
+		for i, x := range vec {
+			if actual := grad[variable][i]; math.Abs(actual-x) > 1e-8 {
+				t.Errorf("expected %f but got %f", x, actual)
+			}
+		}
+	}
+}
+
+type adamVariant interface {
+	Gradienter
+	StateSaver
+}
+
+func TestAdamVariants(t *testing.T) {
+	newVariants := func(g Gradienter) map[string]adamVariant {
+		return map[string]adamVariant{
+			"Adam":    &Adam{Gradienter: g},
+			"AMSGrad": &AMSGrad{Adam: Adam{Gradienter: g}},
+			"Adamax":  &Adamax{Adam: Adam{Gradienter: g}},
+			"NAdam":   &NAdam{Adam: Adam{Gradienter: g}},
+			"RAdam":   &RAdam{Adam: Adam{Gradienter: g}},
+		}
+	}
+
+	for name := range newVariants(nil) {
+		q := newQuadraticTestGradienter()
+		variant := newVariants(q)[name]
+		initCost := q.Cost(nil)
+		if cost := runQuadraticTest(q, variant, 2000, 0.01); cost > initCost*1e-3 {
+			t.Errorf("%s: cost went from %f to %f", name, initCost, cost)
+		}
+	}
+
+	for name := range newVariants(nil) {
+		q1 := newQuadraticTestGradienter()
+		v1 := newVariants(q1)[name]
+		runQuadraticTest(q1, v1, 10, 0.01)
+
+		var buf bytes.Buffer
+		if err := v1.SaveState(&buf, q1.Parameters()); err != nil {
+			t.Fatal(err)
+		}
+		q2 := newQuadraticTestGradienter()
+		copy(q2.Var.Vector, q1.Var.Vector)
+		v2 := newVariants(q2)[name]
+		if err := v2.LoadState(&buf, q2.Parameters()); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		runQuadraticTest(q1, v1, 10, 0.01)
+		runQuadraticTest(q2, v2, 10, 0.01)
+		for i, x := range q1.Var.Vector {
+			if q2.Var.Vector[i] != x {
+				t.Errorf("%s: entry %d: expected %f but got %f", name, i, x, q2.Var.Vector[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package sgd
+
+import (
+	"io"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// AMSGrad implements the variant of Adam described in
+// https://openreview.net/forum?id=ryQu7f-RZ, which
+// divides by the maximum of all the second moment
+// estimates so far, rather than the current estimate.
+//
+// The DecayRate1, DecayRate2, and Damping fields of the
+// embedded Adam are used, with the same defaults.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type AMSGrad struct {
+	Adam
+
+	maxSecondMoment autofunc.Gradient
+}
+
+func (a *AMSGrad) Gradient(s SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+func (a *AMSGrad) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	a.updateMoments(grad)
+	if a.maxSecondMoment == nil {
+		a.maxSecondMoment = a.secondMoment.Copy()
+	} else {
+		for variable, vec := range a.secondMoment {
+			maxVec := a.maxSecondMoment[variable]
+			for i, x := range vec {
+				maxVec[i] = math.Max(maxVec[i], x)
+			}
+		}
+	}
+
+	a.iteration++
+	scalingFactor := math.Sqrt(1-math.Pow(a.decayRate(2), a.iteration)) /
+		(1 - math.Pow(a.decayRate(1), a.iteration))
+	damping := a.damping()
+	for variable, vec := range grad {
+		firstVec := a.firstMoment[variable]
+		maxVec := a.maxSecondMoment[variable]
+		for i, x := range firstVec {
+			vec[i] = scalingFactor * x / math.Sqrt(maxVec[i]+damping)
+		}
+	}
+	return grad
+}
+
+// SaveState saves the moment estimates and iteration
+// count.
+func (a *AMSGrad) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return a.saveMoments(w, "AMSGrad", params, a.maxSecondMoment)
+}
+
+// LoadState loads state saved with SaveState.
+func (a *AMSGrad) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return a.loadMoments(r, "AMSGrad", params, &a.maxSecondMoment)
+}
+
+// Adamax implements the variant of Adam based on the
+// infinity norm, as described in section 7.1 of
+// https://arxiv.org/pdf/1412.6980.pdf.
+//
+// Rather than a second moment, Adamax tracks an
+// exponentially weighted maximum of the absolute values
+// of the gradient, decayed by DecayRate2.
+// The DecayRate1, DecayRate2, and Damping fields of the
+// embedded Adam are used, with the same defaults.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type Adamax struct {
+	Adam
+
+	maxNorm autofunc.Gradient
+}
+
+func (a *Adamax) Gradient(s SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+func (a *Adamax) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	a.updateFirstMoment(grad)
+	if a.maxNorm == nil {
+		a.maxNorm = grad.Copy()
+		for _, vec := range a.maxNorm {
+			for i, x := range vec {
+				vec[i] = math.Abs(x)
+			}
+		}
+	} else {
+		decayRate := a.decayRate(2)
+		for variable, vec := range grad {
+			normVec := a.maxNorm[variable]
+			for i, x := range vec {
+				normVec[i] = math.Max(decayRate*normVec[i], math.Abs(x))
+			}
+		}
+	}
+
+	a.iteration++
+	scalingFactor := 1 / (1 - math.Pow(a.decayRate(1), a.iteration))
+	damping := a.damping()
+	for variable, vec := range grad {
+		firstVec := a.firstMoment[variable]
+		normVec := a.maxNorm[variable]
+		for i, x := range firstVec {
+			vec[i] = scalingFactor * x / (normVec[i] + damping)
+		}
+	}
+	return grad
+}
+
+// SaveState saves the moment estimates and iteration
+// count.
+func (a *Adamax) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return a.saveMoments(w, "Adamax", params, a.maxNorm)
+}
+
+// LoadState loads state saved with SaveState.
+func (a *Adamax) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return a.loadMoments(r, "Adamax", params, &a.maxNorm)
+}
+
+// NAdam implements Adam with Nesterov momentum, as
+// described in
+// http://cs229.stanford.edu/proj2015/054_report.pdf.
+//
+// The first moment used for each step looks ahead by
+// combining the bias-corrected moment estimate for the
+// next iteration with the current gradient.
+// The DecayRate1, DecayRate2, and Damping fields of the
+// embedded Adam are used, with the same defaults.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type NAdam struct {
+	Adam
+}
+
+func (n *NAdam) Gradient(s SampleSet) autofunc.Gradient {
+	return n.Transform(n.Gradienter.Gradient(s))
+}
+
+func (n *NAdam) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	n.updateMoments(grad)
+
+	n.iteration++
+	decay1 := n.decayRate(1)
+	momentScale := decay1 / (1 - math.Pow(decay1, n.iteration+1))
+	gradScale := (1 - decay1) / (1 - math.Pow(decay1, n.iteration))
+	secondScale := 1 / (1 - math.Pow(n.decayRate(2), n.iteration))
+	damping := n.damping()
+	for variable, vec := range grad {
+		firstVec := n.firstMoment[variable]
+		secondVec := n.secondMoment[variable]
+		for i, x := range firstVec {
+			lookahead := momentScale*x + gradScale*vec[i]
+			vec[i] = lookahead / math.Sqrt(secondScale*secondVec[i]+damping)
+		}
+	}
+	return grad
+}
+
+// SaveState saves the moment estimates and iteration
+// count.
+func (n *NAdam) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return n.saveMoments(w, "NAdam", params)
+}
+
+// LoadState loads state saved with SaveState.
+func (n *NAdam) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return n.loadMoments(r, "NAdam", params)
+}
+
+// RAdam implements rectified Adam, as described in
+// https://arxiv.org/abs/1908.03265.
+//
+// RAdam scales Adam's step by a factor which corrects for
+// the variance of the second moment estimate.
+// For the first few iterations, when the variance is
+// intractable, RAdam falls back on the bias-corrected
+// first moment (i.e. SGD with momentum).
+// The DecayRate1, DecayRate2, and Damping fields of the
+// embedded Adam are used, with the same defaults.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type RAdam struct {
+	Adam
+}
+
+func (r *RAdam) Gradient(s SampleSet) autofunc.Gradient {
+	return r.Transform(r.Gradienter.Gradient(s))
+}
+
+func (r *RAdam) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	r.updateMoments(grad)
+
+	r.iteration++
+	decay2 := r.decayRate(2)
+	decay2Pow := math.Pow(decay2, r.iteration)
+	firstScale := 1 / (1 - math.Pow(r.decayRate(1), r.iteration))
+
+	// rho is the length of the approximated simple moving
+	// average which the second moment corresponds to.
+	maxRho := 2/(1-decay2) - 1
+	rho := maxRho - 2*r.iteration*decay2Pow/(1-decay2Pow)
+
+	if rho <= 4 {
+		for variable, vec := range grad {
+			for i, x := range r.firstMoment[variable] {
+				vec[i] = firstScale * x
+			}
+		}
+		return grad
+	}
+
+	rectifier := math.Sqrt((rho - 4) * (rho - 2) * maxRho / ((maxRho - 4) * (maxRho - 2) * rho))
+	scalingFactor := rectifier * firstScale * math.Sqrt(1-decay2Pow)
+	damping := r.damping()
+	for variable, vec := range grad {
+		firstVec := r.firstMoment[variable]
+		secondVec := r.secondMoment[variable]
+		for i, x := range firstVec {
+			vec[i] = scalingFactor * x / math.Sqrt(secondVec[i]+damping)
+		}
+	}
+	return grad
+}
+
+// SaveState saves the moment estimates and iteration
+// count.
+func (r *RAdam) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return r.saveMoments(w, "RAdam", params)
+}
+
+// LoadState loads state saved with SaveState.
+func (r *RAdam) LoadState(rd io.Reader, params []*autofunc.Variable) error {
+	return r.loadMoments(rd, "RAdam", params)
+}
@@ -0,0 +1,55 @@
+package sgd
+
+import "github.com/unixpickle/autofunc"
+
+// AdamW implements Adam with decoupled weight decay, as
+// described in https://arxiv.org/abs/1711.05101.
+//
+// Rather than adding an L2 penalty to the gradient (which
+// Adam would rescale), AdamW adds WeightDecay times each
+// parameter to the transformed gradient.
+// Since the result is scaled by the step size, each
+// update shrinks the parameters by a factor of
+// (1 - stepSize*WeightDecay), independently of the
+// moment estimates.
+//
+// The parameters are read from the variables which key
+// the gradient, so they should not be changed between
+// computing a gradient and transforming it.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+// AdamW saves and loads its state like Adam.
+type AdamW struct {
+	Adam
+
+	// WeightDecay is the decay rate.
+	// If it is 0, AdamW is equivalent to Adam.
+	WeightDecay float64
+
+	// NoDecay contains variables which should not be
+	// decayed, such as biases and normalization
+	// parameters.
+	NoDecay map[*autofunc.Variable]bool
+}
+
+func (a *AdamW) Gradient(s SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+func (a *AdamW) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	grad = a.Adam.Transform(grad)
+	for variable, vec := range grad {
+		if a.NoDecay[variable] {
+			continue
+		}
+		for i, x := range variable.Vector {
+			vec[i] += a.WeightDecay * x
+		}
+	}
+	return grad
+}
@@ -2,6 +2,7 @@ package asyncsgd
 
 import (
 	"errors"
+	"io"
 
 	"github.com/unixpickle/autofunc"
 	"github.com/unixpickle/sgd"
@@ -17,6 +18,10 @@ type Slave struct {
 	miniBatch sgd.SampleSet
 	samples   sgd.SampleSet
 	sampleIdx int
+	rand      *sgd.RandSource
+
+	source sgd.BatchSource
+	err    error
 
 	accumGrad autofunc.Gradient
 }
@@ -24,6 +29,15 @@ type Slave struct {
 // NewSlave creates a slave for a training scenario.
 func NewSlave(g sgd.Gradienter, s sgd.SampleSet, batchSize int, c *ParamClient,
 	p []*autofunc.Variable) *Slave {
+	return NewSlaveRand(g, s, batchSize, c, p, nil)
+}
+
+// NewSlaveRand is like NewSlave, but the slave shuffles
+// its samples using r rather than the global source.
+// Slaves created with identically-seeded sources will
+// visit mini-batches in the same order.
+func NewSlaveRand(g sgd.Gradienter, s sgd.SampleSet, batchSize int, c *ParamClient,
+	p []*autofunc.Variable, r *sgd.RandSource) *Slave {
 	res := &Slave{
 		batchSize:  batchSize,
 		client:     c,
@@ -32,11 +46,39 @@ func NewSlave(g sgd.Gradienter, s sgd.SampleSet, batchSize int, c *ParamClient,
 
 		samples:   s,
 		sampleIdx: s.Len(),
+		rand:      r,
 	}
 	res.cycleMinibatch()
 	return res
 }
 
+// NewSlaveSource creates a slave which trains on
+// mini-batches from a BatchSource rather than from an
+// in-memory SampleSet.
+//
+// If the BatchSource fails, the slave stops training and
+// the error is returned by Loop and Err.
+func NewSlaveSource(g sgd.Gradienter, src sgd.BatchSource, c *ParamClient,
+	p []*autofunc.Variable) (*Slave, error) {
+	res := &Slave{
+		client:     c,
+		gradienter: g,
+		params:     p,
+		source:     src,
+	}
+	res.cycleMinibatch()
+	if res.err != nil {
+		return nil, res.err
+	}
+	return res, nil
+}
+
+// Err returns the error that stopped a slave from reading
+// mini-batches, if there was one.
+func (s *Slave) Err() error {
+	return s.err
+}
+
 // Batch returns a copy of the current minibatch.
 func (s *Slave) Batch() sgd.SampleSet {
 	return s.miniBatch.Copy()
@@ -45,8 +87,13 @@ func (s *Slave) Batch() sgd.SampleSet {
 // Step runs SGD on the next mini-batch and advances to
 // the next mini-batch.
 func (s *Slave) Step() {
+	if s.err != nil {
+		return
+	}
 	batchGrad := s.gradienter.Gradient(s.Batch())
-	if s.accumGrad == nil {
+	if batchGrad == nil {
+		// The gradienter is accumulating gradients.
+	} else if s.accumGrad == nil {
 		s.accumGrad = batchGrad.Copy()
 	} else {
 		s.accumGrad.Add(batchGrad)
@@ -80,6 +127,9 @@ func (s *Slave) Loop(syncInterval int, logFunc func(next, last sgd.SampleSet)) e
 	var unsyncCount int
 	var last sgd.SampleSet
 	for {
+		if s.err != nil {
+			return s.err
+		}
 		next := s.Batch()
 		if logFunc != nil {
 			logFunc(next, last)
@@ -97,9 +147,13 @@ func (s *Slave) Loop(syncInterval int, logFunc func(next, last sgd.SampleSet)) e
 }
 
 func (s *Slave) cycleMinibatch() {
+	if s.source != nil {
+		s.miniBatch, s.err = nextSourceBatch(s.source)
+		return
+	}
 	if s.miniBatch == nil || s.sampleIdx+s.miniBatch.Len() >= s.samples.Len() {
 		s.sampleIdx = 0
-		sgd.ShuffleSampleSet(s.samples)
+		sgd.ShuffleSampleSetRand(s.samples, s.rand)
 	} else {
 		s.sampleIdx += s.miniBatch.Len()
 	}
@@ -109,3 +163,19 @@ func (s *Slave) cycleMinibatch() {
 	}
 	s.miniBatch = s.samples.Subset(s.sampleIdx, s.sampleIdx+bs)
 }
+
+// nextSourceBatch reads the next mini-batch from a
+// BatchSource, skipping over the ends of epochs.
+func nextSourceBatch(src sgd.BatchSource) (sgd.SampleSet, error) {
+	batch, err := src.NextBatch()
+	if err == io.EOF {
+		batch, err = src.NextBatch()
+		if err == io.EOF {
+			return nil, errors.New("batch source is empty")
+		}
+	}
+	if err != nil {
+		return nil, errors.New("next batch: " + err.Error())
+	}
+	return batch, nil
+}
@@ -18,13 +18,46 @@ type Updater interface {
 
 // A TransformerUpdater updates parameters by feeding them
 // into an sgd.Transformer and then doing an SGD step.
+//
+// Since Update owns the parameters, the Transformer may
+// read them through the variables which key the gradient,
+// as sgd.LARS and sgd.LAMB do.
 type TransformerUpdater struct {
 	StepSize    float64
 	Transformer sgd.Transformer
+
+	// Schedule, if non-nil, is used to determine the step
+	// size for each update, in which case StepSize is
+	// ignored.
+	// Each call to Update counts as one step, unless the
+	// Transformer returns nil (as sgd.Accumulator does
+	// while it accumulates gradients).
+	Schedule sgd.Schedule
+
+	// StepsPerEpoch is the number of updates which make up
+	// an epoch, for use with Schedule.
+	// If it is 0, the epoch is always reported as 0.
+	StepsPerEpoch int
+
+	steps int
 }
 
 // Update applies the gradienter to g and descends along
 // the resulting gradient.
 func (g *TransformerUpdater) Update(grad autofunc.Gradient) {
-	g.Transformer.Transform(grad).AddToVars(-g.StepSize)
+	if res := g.Transformer.Transform(grad); res != nil {
+		res.AddToVars(-g.stepSize())
+		g.steps++
+	}
+}
+
+func (g *TransformerUpdater) stepSize() float64 {
+	if g.Schedule == nil {
+		return g.StepSize
+	}
+	var epoch int
+	if g.StepsPerEpoch != 0 {
+		epoch = g.steps / g.StepsPerEpoch
+	}
+	return g.Schedule.StepSize(g.steps, epoch)
 }
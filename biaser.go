@@ -1,6 +1,10 @@
 package sgd
 
-import "github.com/unixpickle/autofunc"
+import (
+	"io"
+
+	"github.com/unixpickle/autofunc"
+)
 
 // A Biaser scales some variables in a gradient, leaving
 // the rest unchanged.
@@ -41,3 +45,14 @@ func (b *Biaser) Transform(res autofunc.Gradient) autofunc.Gradient {
 	}
 	return res
 }
+
+// SaveState writes an empty state.
+// The scales are configuration, not training state.
+func (b *Biaser) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return saveStateless(w, "Biaser")
+}
+
+// LoadState reads a state saved with SaveState.
+func (b *Biaser) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return loadStateless(r, "Biaser")
+}
@@ -0,0 +1,196 @@
+package sgd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// A Checkpoint records everything needed to resume a
+// Trainer: the parameters, the training counters, the
+// shuffling state, and the state of any optimizers.
+//
+// When the Trainer has a Rand and every stateful
+// Gradienter it uses is checkpointed, restoring a
+// Checkpoint and calling Run produces the same parameters
+// as if training had never been interrupted.
+type Checkpoint struct {
+	Params []linalg.Vector
+
+	Step      int
+	Epoch     int
+	EpochStep int
+
+	// HasRand indicates whether RandState is meaningful.
+	HasRand bool
+
+	// RandState is the state of the Trainer's Rand at the
+	// start of the current (possibly partial) epoch.
+	RandState uint64
+
+	// States stores the encoded state of each optimizer,
+	// in the order they were passed to NewCheckpoint.
+	States [][]byte
+}
+
+// NewCheckpoint captures the current state of training.
+//
+// The optimizers' states are saved in terms of the order
+// of l.Parameters().
+func NewCheckpoint(t *Trainer, l Learner, optimizers ...StateSaver) (*Checkpoint, error) {
+	params := l.Parameters()
+	res := &Checkpoint{
+		Params:    snapshotParams(params),
+		Step:      t.Step,
+		Epoch:     t.Epoch,
+		EpochStep: t.EpochStep,
+	}
+	if t.Rand != nil {
+		res.HasRand = true
+		if t.EpochStep == 0 {
+			res.RandState = t.Rand.State()
+		} else {
+			res.RandState = t.epochRandState
+		}
+	}
+	for _, o := range optimizers {
+		var buf bytes.Buffer
+		if err := o.SaveState(&buf, params); err != nil {
+			return nil, err
+		}
+		res.States = append(res.States, buf.Bytes())
+	}
+	return res, nil
+}
+
+// Restore loads the checkpoint into a Trainer, a Learner,
+// and a list of optimizers.
+//
+// The optimizers must be passed in the same order as they
+// were passed to NewCheckpoint.
+func (c *Checkpoint) Restore(t *Trainer, l Learner, optimizers ...StateSaver) error {
+	params := l.Parameters()
+	if len(params) != len(c.Params) {
+		return errStateDimensions
+	}
+	for i, p := range params {
+		if len(p.Vector) != len(c.Params[i]) {
+			return errStateDimensions
+		}
+	}
+	if len(optimizers) != len(c.States) {
+		return errors.New("optimizer count does not match checkpoint")
+	}
+	if c.HasRand && t.Rand == nil {
+		return errors.New("checkpoint requires a Trainer with a Rand")
+	}
+	for i, o := range optimizers {
+		if err := o.LoadState(bytes.NewReader(c.States[i]), params); err != nil {
+			return err
+		}
+	}
+	restoreParams(params, c.Params)
+	t.Step = c.Step
+	t.Epoch = c.Epoch
+	t.EpochStep = c.EpochStep
+	if c.HasRand {
+		t.Rand.SetState(c.RandState)
+		t.epochRandState = c.RandState
+	}
+	return nil
+}
+
+// WriteTo encodes the checkpoint in a versioned binary
+// format.
+func (c *Checkpoint) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	writeStateHeader(&buf, "Checkpoint")
+	for _, x := range []int{c.Step, c.Epoch, c.EpochStep} {
+		writeStateInt(&buf, x)
+	}
+	writeStateBool(&buf, c.HasRand)
+	binary.Write(&buf, stateByteOrder, c.RandState)
+	writeStateInt(&buf, len(c.Params))
+	for _, p := range c.Params {
+		writeStateInt(&buf, len(p))
+		binary.Write(&buf, stateByteOrder, []float64(p))
+	}
+	writeStateInt(&buf, len(c.States))
+	for _, s := range c.States {
+		writeStateInt(&buf, len(s))
+		buf.Write(s)
+	}
+	return buf.WriteTo(w)
+}
+
+// ReadCheckpoint decodes a checkpoint encoded with
+// Checkpoint.WriteTo.
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	if err := readStateHeader(r, "Checkpoint"); err != nil {
+		return nil, err
+	}
+	res := &Checkpoint{}
+	for _, x := range []*int{&res.Step, &res.Epoch, &res.EpochStep} {
+		var err error
+		if *x, err = readStateInt(r); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if res.HasRand, err = readStateBool(r); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, stateByteOrder, &res.RandState); err != nil {
+		return nil, err
+	}
+
+	numParams, err := readStateCount(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numParams; i++ {
+		size, err := readStateCount(r)
+		if err != nil {
+			return nil, err
+		}
+		vec := make(linalg.Vector, size)
+		if err := binary.Read(r, stateByteOrder, []float64(vec)); err != nil {
+			return nil, err
+		}
+		res.Params = append(res.Params, vec)
+	}
+
+	numStates, err := readStateCount(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numStates; i++ {
+		size, err := readStateCount(r)
+		if err != nil {
+			return nil, err
+		}
+		state := make([]byte, size)
+		if _, err := io.ReadFull(r, state); err != nil {
+			return nil, err
+		}
+		res.States = append(res.States, state)
+	}
+	return res, nil
+}
+
+// readStateCount reads a length or count, guarding
+// against corrupt values which would cause huge
+// allocations.
+func readStateCount(r io.Reader) (int, error) {
+	n, err := readStateInt(r)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 1<<30 {
+		return 0, errors.New("invalid length in checkpoint")
+	}
+	return n, nil
+}
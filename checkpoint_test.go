@@ -0,0 +1,153 @@
+package sgd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// targetTestGradienter computes the gradient of the sum of
+// squared distances between its parameters and the
+// samples, each of which is a float64.
+type targetTestGradienter struct {
+	Vars []*autofunc.Variable
+}
+
+func newTargetTestGradienter() *targetTestGradienter {
+	return &targetTestGradienter{
+		Vars: []*autofunc.Variable{
+			{Vector: []float64{1, -2, 3}},
+			{Vector: []float64{0.5}},
+		},
+	}
+}
+
+func (t *targetTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	grad := autofunc.NewGradient(t.Vars)
+	for i := 0; i < s.Len(); i++ {
+		target := s.GetSample(i).(float64)
+		for _, v := range t.Vars {
+			for j, x := range v.Vector {
+				grad[v][j] += x - target
+			}
+		}
+	}
+	return grad
+}
+
+func (t *targetTestGradienter) Cost(s SampleSet) float64 {
+	var res float64
+	for i := 0; i < s.Len(); i++ {
+		target := s.GetSample(i).(float64)
+		for _, v := range t.Vars {
+			for _, x := range v.Vector {
+				res += 0.5 * (x - target) * (x - target)
+			}
+		}
+	}
+	return res
+}
+
+func (t *targetTestGradienter) Parameters() []*autofunc.Variable {
+	return t.Vars
+}
+
+func TestCheckpointResume(t *testing.T) {
+	samples := SliceSampleSet{}
+	for i := 0; i < 17; i++ {
+		samples = append(samples, float64(i%5)-2)
+	}
+	newTrainer := func() (*Trainer, *targetTestGradienter, *Adam) {
+		g := newTargetTestGradienter()
+		adam := &Adam{Gradienter: g}
+		return &Trainer{
+			Gradienter: adam,
+			Samples:    samples,
+			BatchSize:  3,
+			Schedule:   ConstantSchedule(0.01),
+			Rand:       NewRandSource(1337),
+		}, g, adam
+	}
+
+	expected, expectedG, _ := newTrainer()
+	expected.Budget.Steps = 20
+	expected.Run(context.Background())
+
+	first, firstG, firstAdam := newTrainer()
+	first.Budget.Steps = 8
+	first.Run(context.Background())
+	checkpoint, err := NewCheckpoint(first, firstG, firstAdam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := checkpoint.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadCheckpoint(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, secondG, secondAdam := newTrainer()
+	second.Rand = NewRandSource(0)
+	if err := decoded.Restore(second, secondG, secondAdam); err != nil {
+		t.Fatal(err)
+	}
+	second.Budget.Steps = 12
+	second.Run(context.Background())
+
+	if second.Step != expected.Step || second.Epoch != expected.Epoch {
+		t.Fatalf("expected step %d epoch %d but got step %d epoch %d",
+			expected.Step, expected.Epoch, second.Step, second.Epoch)
+	}
+	for i, v := range expectedG.Vars {
+		for j, x := range v.Vector {
+			if a := secondG.Vars[i].Vector[j]; a != x {
+				t.Errorf("var %d entry %d: expected %v but got %v", i, j, x, a)
+			}
+		}
+	}
+}
+
+func TestStateMismatch(t *testing.T) {
+	g := newTargetTestGradienter()
+	adam := &Adam{Gradienter: g}
+	adam.Gradient(SliceSampleSet{1.0})
+	var buf bytes.Buffer
+	if err := adam.SaveState(&buf, g.Vars); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Momentum{}).LoadState(&buf, g.Vars); err == nil {
+		t.Error("expected error loading Adam state into Momentum")
+	}
+}
+
+func TestStateDimensions(t *testing.T) {
+	g := newTargetTestGradienter()
+	adam := &Adam{Gradienter: g}
+	adam.Gradient(SliceSampleSet{1.0})
+	var buf bytes.Buffer
+	if err := adam.SaveState(&buf, g.Vars); err != nil {
+		t.Fatal(err)
+	}
+	other := newTargetTestGradienter()
+	other.Vars[0].Vector = other.Vars[0].Vector[:2]
+	if err := (&Adam{}).LoadState(&buf, other.Vars); err != errStateDimensions {
+		t.Errorf("expected errStateDimensions but got %v", err)
+	}
+}
+
+func TestStateVersion(t *testing.T) {
+	for _, version := range []uint32{0, stateVersion + 1} {
+		var buf bytes.Buffer
+		binary.Write(&buf, stateByteOrder, version)
+		writeStateString(&buf, "Momentum")
+		if err := (&Momentum{}).LoadState(&buf, nil); err == nil {
+			t.Errorf("expected error for version %d", version)
+		}
+	}
+}
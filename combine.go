@@ -0,0 +1,207 @@
+package sgd
+
+import (
+	"fmt"
+	"math"
+)
+
+// ConcatSampleSets creates a SampleSet containing the
+// samples of every set, one set after another.
+//
+// The sets are copied, so they may be reordered later
+// without affecting the result.
+// If every set is a Hasher, the result is a Hasher.
+func ConcatSampleSets(sets ...SampleSet) SampleSet {
+	res := newRefSampleSet(sets)
+	for i, s := range res.sets {
+		for j := 0; j < s.Len(); j++ {
+			res.refs = append(res.refs, sampleRef{i, j})
+		}
+	}
+	return res.wrap()
+}
+
+// FilterSampleSet creates a SampleSet containing the
+// samples from s for which pred returns true, in order.
+//
+// The predicate is evaluated once for every sample when
+// FilterSampleSet is called, not when samples are
+// accessed.
+// If s is a Hasher, the result is a Hasher.
+func FilterSampleSet(s SampleSet, pred func(sample interface{}) bool) SampleSet {
+	res := newRefSampleSet([]SampleSet{s})
+	for i := 0; i < s.Len(); i++ {
+		if pred(s.GetSample(i)) {
+			res.refs = append(res.refs, sampleRef{0, i})
+		}
+	}
+	return res.wrap()
+}
+
+// MapSampleSet creates a SampleSet whose samples are the
+// results of applying f to the samples of s.
+//
+// The function is applied every time a sample is
+// accessed, so it should be cheap and deterministic.
+// The result is never a Hasher, since f may change the
+// contents of the samples.
+func MapSampleSet(s SampleSet, f func(sample interface{}) interface{}) SampleSet {
+	res := newRefSampleSet([]SampleSet{s})
+	res.mapFunc = f
+	res.refs = make([]sampleRef, s.Len())
+	for i := range res.refs {
+		res.refs[i] = sampleRef{0, i}
+	}
+	return res
+}
+
+// RepeatSampleSet creates a SampleSet in which every
+// sample of s appears count times.
+//
+// This can be used to oversample a small data set before
+// concatenating it with a larger one.
+// If s is a Hasher, the result is a Hasher, and repeated
+// samples have the same hash.
+// If count is 0, the result is empty.
+// RepeatSampleSet panics if count is negative.
+func RepeatSampleSet(s SampleSet, count int) SampleSet {
+	if count < 0 {
+		panic("repeat count must be non-negative")
+	}
+	res := newRefSampleSet([]SampleSet{s})
+	res.refs = make([]sampleRef, 0, s.Len()*count)
+	for i := 0; i < count; i++ {
+		for j := 0; j < s.Len(); j++ {
+			res.refs = append(res.refs, sampleRef{0, j})
+		}
+	}
+	return res.wrap()
+}
+
+// MixSampleSets creates a SampleSet with (roughly) size
+// samples, drawn from the sets in proportion to their
+// weights.
+//
+// Samples are taken from each set in order, cycling back
+// to the start of a set if it is too small to provide its
+// share, so small sets are oversampled and large sets are
+// undersampled.
+// To draw a different subset of an undersampled set,
+// shuffle it before mixing.
+//
+// If every set is a Hasher, the result is a Hasher.
+// MixSampleSets panics if the number of sets and weights
+// differ, if a weight is negative, or if a set with a
+// positive weight is empty.
+func MixSampleSets(sets []SampleSet, weights []float64, size int) SampleSet {
+	if len(sets) != len(weights) {
+		panic("number of sets must match number of weights")
+	}
+	var total float64
+	for _, w := range weights {
+		if w < 0 {
+			panic("weights must be non-negative")
+		}
+		total += w
+	}
+	res := newRefSampleSet(sets)
+	if total == 0 {
+		return res.wrap()
+	}
+	for i, s := range res.sets {
+		count := int(math.Floor(float64(size)*weights[i]/total + 0.5))
+		if count > 0 && s.Len() == 0 {
+			panic(fmt.Sprintf("sample set %d is empty", i))
+		}
+		for j := 0; j < count; j++ {
+			res.refs = append(res.refs, sampleRef{i, j % s.Len()})
+		}
+	}
+	return res.wrap()
+}
+
+type sampleRef struct {
+	set int
+	idx int
+}
+
+// refSampleSet is a SampleSet which refers to the samples
+// of other sets.
+//
+// The referenced sets are never modified, so Swap and
+// Subset only affect the list of references, and the
+// aliasing rules of a refSampleSet are the same as those
+// of a SliceSampleSet.
+type refSampleSet struct {
+	sets    []SampleSet
+	refs    []sampleRef
+	mapFunc func(sample interface{}) interface{}
+}
+
+func newRefSampleSet(sets []SampleSet) *refSampleSet {
+	res := &refSampleSet{sets: make([]SampleSet, len(sets))}
+	for i, s := range sets {
+		res.sets[i] = s.Copy()
+	}
+	return res
+}
+
+// wrap returns a Hasher if every referenced set is a
+// Hasher (after being copied), or the receiver otherwise.
+func (r *refSampleSet) wrap() SampleSet {
+	if r.mapFunc != nil {
+		return r
+	}
+	for _, s := range r.sets {
+		if _, ok := s.(Hasher); !ok {
+			return r
+		}
+	}
+	return &hashRefSampleSet{r}
+}
+
+func (r *refSampleSet) Len() int {
+	return len(r.refs)
+}
+
+func (r *refSampleSet) Copy() SampleSet {
+	return r.withRefs(append([]sampleRef{}, r.refs...))
+}
+
+func (r *refSampleSet) Swap(i, j int) {
+	r.refs[i], r.refs[j] = r.refs[j], r.refs[i]
+}
+
+func (r *refSampleSet) GetSample(idx int) interface{} {
+	ref := r.refs[idx]
+	sample := r.sets[ref.set].GetSample(ref.idx)
+	if r.mapFunc != nil {
+		return r.mapFunc(sample)
+	}
+	return sample
+}
+
+func (r *refSampleSet) Subset(start, end int) SampleSet {
+	return r.withRefs(r.refs[start:end])
+}
+
+func (r *refSampleSet) withRefs(refs []sampleRef) *refSampleSet {
+	return &refSampleSet{sets: r.sets, refs: refs, mapFunc: r.mapFunc}
+}
+
+type hashRefSampleSet struct {
+	*refSampleSet
+}
+
+func (h *hashRefSampleSet) Copy() SampleSet {
+	return &hashRefSampleSet{h.refSampleSet.Copy().(*refSampleSet)}
+}
+
+func (h *hashRefSampleSet) Subset(start, end int) SampleSet {
+	return &hashRefSampleSet{h.withRefs(h.refs[start:end])}
+}
+
+func (h *hashRefSampleSet) Hash(idx int) []byte {
+	ref := h.refs[idx]
+	return h.sets[ref.set].(Hasher).Hash(ref.idx)
+}
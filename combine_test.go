@@ -0,0 +1,109 @@
+package sgd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCombineSampleSets(t *testing.T) {
+	s1 := SliceSampleSet{1, 2, 3}
+	s2 := SliceSampleSet{4, 5}
+
+	concat := ConcatSampleSets(s1, s2)
+	s1.Swap(0, 2)
+	checkSamples(t, "concat", concat, 1, 2, 3, 4, 5)
+
+	sub := concat.Subset(1, 4)
+	concat.Swap(1, 3)
+	checkSamples(t, "subset", sub, 4, 3, 2)
+	checkSamples(t, "copy", concat.Copy().Subset(0, 2), 1, 4)
+
+	even := FilterSampleSet(s2, func(x interface{}) bool {
+		return x.(int)%2 == 0
+	})
+	checkSamples(t, "filter", even, 4)
+
+	doubled := MapSampleSet(s2, func(x interface{}) interface{} {
+		return x.(int) * 2
+	})
+	checkSamples(t, "map", doubled, 8, 10)
+
+	checkSamples(t, "repeat", RepeatSampleSet(s2, 2), 4, 5, 4, 5)
+
+	mix := MixSampleSets([]SampleSet{s1, s2}, []float64{1, 3}, 8)
+	checkSamples(t, "mix", mix, 3, 2, 4, 5, 4, 5, 4, 5)
+}
+
+func TestRepeatSampleSetCount(t *testing.T) {
+	s := SliceSampleSet{4, 5}
+	checkSamples(t, "repeat 0", RepeatSampleSet(s, 0))
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for negative count")
+		}
+	}()
+	RepeatSampleSet(s, -1)
+}
+
+func TestCombineHasher(t *testing.T) {
+	h1 := byteHashSet{[]byte{0x10}, []byte{0x90}}
+	h2 := byteHashSet{[]byte{0x20}}
+
+	concat, ok := ConcatSampleSets(h1, h2).(Hasher)
+	if !ok {
+		t.Fatal("concatenated hashers should be a Hasher")
+	}
+	left, right := HashSplit(concat, 0.5)
+	if left.Len() != 2 || right.Len() != 1 {
+		t.Errorf("unexpected split sizes: %d, %d", left.Len(), right.Len())
+	}
+	if _, ok := left.(Hasher); !ok {
+		t.Error("subset should be a Hasher")
+	}
+
+	if _, ok := ConcatSampleSets(h1, SliceSampleSet{1}).(Hasher); ok {
+		t.Error("mixed concatenation should not be a Hasher")
+	}
+	identity := func(x interface{}) interface{} { return x }
+	if _, ok := MapSampleSet(h1, identity).(Hasher); ok {
+		t.Error("mapped set should not be a Hasher")
+	}
+}
+
+func checkSamples(t *testing.T, name string, s SampleSet, expected ...interface{}) {
+	var actual []interface{}
+	for i := 0; i < s.Len(); i++ {
+		actual = append(actual, s.GetSample(i))
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%s: expected %v but got %v", name, expected, actual)
+	}
+}
+
+// byteHashSet is like hashTestSet, but its copies and
+// subsets are also Hashers.
+type byteHashSet [][]byte
+
+func (b byteHashSet) Len() int {
+	return len(b)
+}
+
+func (b byteHashSet) Copy() SampleSet {
+	return append(byteHashSet{}, b...)
+}
+
+func (b byteHashSet) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b byteHashSet) GetSample(idx int) interface{} {
+	return b[idx]
+}
+
+func (b byteHashSet) Subset(start, end int) SampleSet {
+	return b[start:end]
+}
+
+func (b byteHashSet) Hash(idx int) []byte {
+	return b[idx]
+}
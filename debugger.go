@@ -18,20 +18,46 @@ type Debugger struct {
 	// specific order (the order used to print out
 	// variable-specific information).
 	Parameters []*autofunc.Variable
+
+	// Events, if non-nil, receives a GradientEvent and a
+	// VariableEvent for each parameter on every call to
+	// Gradient, instead of the information being logged.
+	Events EventSink
+
+	calls int
 }
 
 func (d *Debugger) Gradient(s SampleSet) autofunc.Gradient {
 	res := d.Gradienter.Gradient(s)
+	if res == nil {
+		return nil
+	}
+	d.calls++
+
+	gradEvent := gradientStats(res)
+	gradEvent.Call = d.calls
+	if d.Events != nil {
+		d.Events.Emit(gradEvent)
+	} else {
+		log.Printf("Overall mean=%f variance=%f", gradEvent.Mean, gradEvent.Variance)
+	}
 
-	logGradientStats(res)
 	for i, p := range d.Parameters {
-		logVariableStats(p, res[p], i)
+		varEvent := variableStats(p, res[p], i)
+		varEvent.Call = d.calls
+		if d.Events != nil {
+			d.Events.Emit(varEvent)
+		} else {
+			log.Printf("Variable %d: mean=%f variance=%f max=%f grad/val=%f zeroes=%d",
+				i, varEvent.Mean, varEvent.Variance, varEvent.MaxAbs,
+				varEvent.GradOverVal, varEvent.Zeroes)
+		}
 	}
 
 	return res
 }
 
-func logGradientStats(g autofunc.Gradient) {
+func gradientStats(g autofunc.Gradient) *GradientEvent {
 	var mean, variance float64
 	var count int
 	for _, v := range g {
@@ -41,14 +67,15 @@ func logGradientStats(g autofunc.Gradient) {
 			variance += x * x
 		}
 	}
+	norm := math.Sqrt(variance)
 	mean /= float64(count)
 	variance /= float64(count)
 	variance -= mean * mean
 
-	log.Printf("Overall mean=%f variance=%f", mean, variance)
+	return &GradientEvent{Norm: norm, Mean: mean, Variance: variance}
 }
 
-func logVariableStats(v *autofunc.Variable, grad linalg.Vector, idx int) {
+func variableStats(v *autofunc.Variable, grad linalg.Vector, idx int) *VariableEvent {
 	var mean, variance, maxAbs, meanChange float64
 	var zeroCount int
 	for i, x := range grad {
@@ -65,6 +92,12 @@ func logVariableStats(v *autofunc.Variable, grad linalg.Vector, idx int) {
 	meanChange /= float64(len(v.Vector))
 	variance -= mean * mean
 
-	log.Printf("Variable %d: mean=%f variance=%f max=%f grad/val=%f zeroes=%d",
-		idx, mean, variance, maxAbs, meanChange, zeroCount)
+	return &VariableEvent{
+		Index:       idx,
+		Mean:        mean,
+		Variance:    variance,
+		MaxAbs:      maxAbs,
+		GradOverVal: meanChange,
+		Zeroes:      zeroCount,
+	}
 }
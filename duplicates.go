@@ -0,0 +1,367 @@
+package sgd
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// A DuplicatePair identifies two samples which are
+// duplicates (or near duplicates) of each other.
+//
+// For DuplicateFinder.Duplicates, both indices refer to
+// the same set and I < J.
+// For DuplicateFinder.Leaks, I refers to the first set
+// and J to the second.
+type DuplicatePair struct {
+	I int
+	J int
+}
+
+// A DuplicateFinder finds duplicate samples within a
+// SampleSet, or samples which are leaked from one
+// SampleSet to another (e.g. from a training set to a
+// validation set).
+//
+// By default, only exact duplicates are found.
+// Two samples are exact duplicates if their vectors (see
+// Vector) are equal, or, if the samples are compared by
+// hash, if the samples themselves are equal.
+// Either way, a VectorPair is compared by both its input
+// and its output, and hashes are only used to find
+// candidates, which are then checked.
+//
+// If Quantum or Planes is set, samples are grouped into
+// buckets so that similar samples are likely to share a
+// bucket, and samples in the same bucket are reported as
+// near duplicates if they are within MaxDistance of each
+// other.
+// In this case, comparisons are only made within
+// buckets, so some near duplicates may be missed.
+type DuplicateFinder struct {
+	// Vector converts a sample to a vector for comparison.
+	// If it is nil, samples must be linalg.Vectors,
+	// []linalg.Vectors (which are joined into one vector),
+	// or VectorPairs (whose inputs and outputs are joined).
+	// To compare VectorPairs by their inputs alone, set
+	// Vector to a function which returns the input.
+	Vector func(sample interface{}) linalg.Vector
+
+	// Normalize, if true, scales vectors to unit length
+	// before they are compared, so that rescaled copies of
+	// a sample are treated as duplicates.
+	Normalize bool
+
+	// Quantum, if non-zero, enables quantized hashing.
+	// Each component is rounded to the nearest multiple of
+	// Quantum, and samples with the same rounded vector
+	// share a bucket.
+	Quantum float64
+
+	// Planes, if non-zero, enables locality-sensitive
+	// hashing with random hyperplanes.
+	// Samples share a bucket if they are on the same side
+	// of Planes random hyperplanes through the origin.
+	// More planes produce fewer false matches, but miss
+	// more near duplicates.
+	//
+	// The hyperplanes are generated the first time they
+	// are needed, so every vector compared by the finder
+	// must have the same length.
+	// The finder's methods panic if they do not.
+	Planes int
+
+	// Tables is the number of independent sets of
+	// hyperplanes to use.
+	// A pair of samples is compared if it shares a bucket
+	// in any table, so more tables miss fewer near
+	// duplicates.
+	// If it is 0, a default of 1 is used.
+	Tables int
+
+	// MaxDistance is the maximum Euclidean distance
+	// between near duplicates.
+	// If it is 0, all samples which share a bucket are
+	// reported.
+	MaxDistance float64
+
+	// Rand, if non-nil, is used to generate hyperplanes.
+	// Otherwise, the global source from math/rand is used.
+	Rand *RandSource
+
+	planes [][]linalg.Vector
+}
+
+// Duplicates finds pairs of duplicate samples in s.
+//
+// In exact mode, candidates are found by their hashes if
+// s is a Hasher and neither Vector nor Normalize is set,
+// or by HashVectors of their vectors otherwise.
+//
+// The resulting pairs are sorted.
+func (d *DuplicateFinder) Duplicates(s SampleSet) []DuplicatePair {
+	hasher, _ := s.(Hasher)
+	keys, vecs := d.sampleKeys(s, hasher)
+	buckets := map[string][]int{}
+	for i, sampleKeys := range keys {
+		for _, key := range sampleKeys {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+	found := map[DuplicatePair]bool{}
+	for _, indices := range buckets {
+		for a, i := range indices {
+			for _, j := range indices[a+1:] {
+				pair := DuplicatePair{I: i, J: j}
+				if !found[pair] && d.near(s, s, vecs, vecs, i, j) {
+					found[pair] = true
+				}
+			}
+		}
+	}
+	return sortedPairs(found)
+}
+
+// Leaks finds pairs of samples from s1 and s2 which are
+// duplicates of each other.
+//
+// In exact mode, candidates are found by their hashes if
+// both sets are Hashers and neither Vector nor Normalize
+// is set, or by HashVectors of their vectors otherwise.
+//
+// The resulting pairs are sorted.
+func (d *DuplicateFinder) Leaks(s1, s2 SampleSet) []DuplicatePair {
+	h1, ok1 := s1.(Hasher)
+	h2, ok2 := s2.(Hasher)
+	if !ok1 || !ok2 {
+		h1, h2 = nil, nil
+	}
+	keys1, vecs1 := d.sampleKeys(s1, h1)
+	keys2, vecs2 := d.sampleKeys(s2, h2)
+	buckets := map[string][]int{}
+	for i, sampleKeys := range keys1 {
+		for _, key := range sampleKeys {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+	found := map[DuplicatePair]bool{}
+	for j, sampleKeys := range keys2 {
+		for _, key := range sampleKeys {
+			for _, i := range buckets[key] {
+				pair := DuplicatePair{I: i, J: j}
+				if !found[pair] && d.near(s1, s2, vecs1, vecs2, i, j) {
+					found[pair] = true
+				}
+			}
+		}
+	}
+	return sortedPairs(found)
+}
+
+// Dedup returns a copy of s without duplicate samples.
+// The first sample in each group of duplicates is kept.
+func (d *DuplicateFinder) Dedup(s SampleSet) SampleSet {
+	var remove []int
+	for _, pair := range d.Duplicates(s) {
+		remove = append(remove, pair.J)
+	}
+	return removeIndices(s, remove)
+}
+
+// RemoveLeaks returns a copy of s1 without the samples
+// which have duplicates in s2.
+//
+// For example, if s1 is a training set and s2 is a
+// validation set, the result is a training set which
+// does not overlap with the validation set.
+func (d *DuplicateFinder) RemoveLeaks(s1, s2 SampleSet) SampleSet {
+	var remove []int
+	for _, pair := range d.Leaks(s1, s2) {
+		remove = append(remove, pair.I)
+	}
+	return removeIndices(s1, remove)
+}
+
+func (d *DuplicateFinder) exact() bool {
+	return d.Quantum == 0 && d.Planes == 0
+}
+
+// sampleKeys computes the bucket keys for every sample.
+// In exact mode, the hasher is used if it is non-nil and
+// the samples are not transformed into vectors in a
+// custom way.
+// Otherwise, the samples' vectors are returned as well.
+func (d *DuplicateFinder) sampleKeys(s SampleSet, h Hasher) ([][]string, []linalg.Vector) {
+	keys := make([][]string, s.Len())
+	if d.exact() && h != nil && !d.Normalize && d.Vector == nil {
+		for i := range keys {
+			keys[i] = []string{string(h.Hash(i))}
+		}
+		return keys, nil
+	}
+	vecs := make([]linalg.Vector, s.Len())
+	for i := range vecs {
+		vecs[i] = d.sampleVector(s.GetSample(i))
+		keys[i] = d.vectorKeys(vecs[i])
+	}
+	return keys, vecs
+}
+
+func (d *DuplicateFinder) sampleVector(sample interface{}) linalg.Vector {
+	var vec linalg.Vector
+	if d.Vector != nil {
+		vec = d.Vector(sample)
+	} else {
+		switch sample := sample.(type) {
+		case linalg.Vector:
+			vec = sample
+		case []linalg.Vector:
+			for _, v := range sample {
+				vec = append(vec, v...)
+			}
+		case VectorPair:
+			vec = append(append(vec, sample.Input...), sample.Output...)
+		default:
+			panic("unsupported sample type")
+		}
+	}
+	if d.Normalize {
+		if norm := math.Sqrt(vec.Dot(vec)); norm != 0 {
+			vec = vec.Copy().Scale(1 / norm)
+		}
+	}
+	return vec
+}
+
+func (d *DuplicateFinder) vectorKeys(vec linalg.Vector) []string {
+	if d.exact() {
+		return []string{string(HashVectors(vec))}
+	}
+	if d.Quantum != 0 {
+		rounded := make(linalg.Vector, len(vec))
+		for i, x := range vec {
+			rounded[i] = math.Floor(x/d.Quantum + 0.5)
+		}
+		return []string{string(HashVectors(rounded))}
+	}
+	planes := d.hyperplanes(len(vec))
+	res := make([]string, len(planes))
+	for i, table := range planes {
+		key := []byte(strconv.Itoa(i) + ":")
+		for _, plane := range table {
+			if plane.Dot(vec) >= 0 {
+				key = append(key, '1')
+			} else {
+				key = append(key, '0')
+			}
+		}
+		res[i] = string(key)
+	}
+	return res
+}
+
+// hyperplanes generates the hyperplanes the first time
+// they are needed, so that every set compared by the
+// finder is hashed the same way.
+func (d *DuplicateFinder) hyperplanes(dim int) [][]linalg.Vector {
+	if d.planes != nil {
+		if len(d.planes[0][0]) != dim {
+			panic("inconsistent vector dimensions")
+		}
+		return d.planes
+	}
+	normFloat := rand.NormFloat64
+	if d.Rand != nil {
+		normFloat = rand.New(d.Rand).NormFloat64
+	}
+	d.planes = make([][]linalg.Vector, defaultInt(d.Tables, 1))
+	for i := range d.planes {
+		d.planes[i] = make([]linalg.Vector, d.Planes)
+		for j := range d.planes[i] {
+			plane := make(linalg.Vector, dim)
+			for k := range plane {
+				plane[k] = normFloat()
+			}
+			d.planes[i][j] = plane
+		}
+	}
+	return d.planes
+}
+
+// near checks if two samples which share a bucket are
+// duplicates.
+// If the samples were hashed by a Hasher, vecs1 and vecs2
+// are nil and the samples are compared directly.
+func (d *DuplicateFinder) near(s1, s2 SampleSet, vecs1, vecs2 []linalg.Vector,
+	i, j int) bool {
+	if vecs1 == nil {
+		return reflect.DeepEqual(s1.GetSample(i), s2.GetSample(j))
+	}
+	v1, v2 := vecs1[i], vecs2[j]
+	if len(v1) != len(v2) {
+		return false
+	}
+	if d.exact() {
+		for k, x := range v1 {
+			if x != v2[k] {
+				return false
+			}
+		}
+		return true
+	}
+	if d.MaxDistance == 0 {
+		return true
+	}
+	var sqDist float64
+	for k, x := range v1 {
+		diff := x - v2[k]
+		sqDist += diff * diff
+	}
+	return sqDist <= d.MaxDistance*d.MaxDistance
+}
+
+func sortedPairs(pairs map[DuplicatePair]bool) []DuplicatePair {
+	res := make([]DuplicatePair, 0, len(pairs))
+	for pair := range pairs {
+		res = append(res, pair)
+	}
+	sort.Sort(duplicatePairs(res))
+	return res
+}
+
+type duplicatePairs []DuplicatePair
+
+func (d duplicatePairs) Len() int {
+	return len(d)
+}
+
+func (d duplicatePairs) Less(i, j int) bool {
+	if d[i].I != d[j].I {
+		return d[i].I < d[j].I
+	}
+	return d[i].J < d[j].J
+}
+
+func (d duplicatePairs) Swap(i, j int) {
+	d[i], d[j] = d[j], d[i]
+}
+
+// removeIndices creates a copy of s without the samples
+// at the given indices.
+func removeIndices(s SampleSet, indices []int) SampleSet {
+	remove := map[int]bool{}
+	for _, idx := range indices {
+		remove[idx] = true
+	}
+	res := newRefSampleSet([]SampleSet{s})
+	for i := 0; i < s.Len(); i++ {
+		if !remove[i] {
+			res.refs = append(res.refs, sampleRef{0, i})
+		}
+	}
+	return res.wrap()
+}
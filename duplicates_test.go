@@ -0,0 +1,140 @@
+package sgd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestDuplicatesExact(t *testing.T) {
+	samples := SliceSampleSet{
+		linalg.Vector{1, 2},
+		linalg.Vector{3, 4},
+		linalg.Vector{1, 2},
+		linalg.Vector{1, 2.0001},
+		linalg.Vector{3, 4},
+	}
+	finder := &DuplicateFinder{}
+	expected := []DuplicatePair{{0, 2}, {1, 4}}
+	if pairs := finder.Duplicates(samples); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v but got %v", expected, pairs)
+	}
+	hashed := VectorSet{Samples: samples}
+	if pairs := finder.Duplicates(hashed); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Hasher: expected %v but got %v", expected, pairs)
+	}
+	deduped := finder.Dedup(hashed)
+	if deduped.Len() != 3 {
+		t.Errorf("expected 3 samples but got %d", deduped.Len())
+	}
+	if _, ok := deduped.(Hasher); !ok {
+		t.Error("deduplicated Hasher should be a Hasher")
+	}
+}
+
+func TestDuplicatesNear(t *testing.T) {
+	train := SliceSampleSet{
+		linalg.Vector{1, 2, 3},
+		linalg.Vector{-5, 0, 1},
+		linalg.Vector{0.3, 0.2, -0.7},
+	}
+	validation := SliceSampleSet{
+		linalg.Vector{-5, 1e-6, 1},
+		linalg.Vector{2, 4, 6},
+		linalg.Vector{7, -1, 1},
+	}
+
+	quantized := &DuplicateFinder{Quantum: 0.01, MaxDistance: 0.001}
+	expected := []DuplicatePair{{1, 0}}
+	if pairs := quantized.Leaks(train, validation); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("quantized: expected %v but got %v", expected, pairs)
+	}
+
+	lsh := &DuplicateFinder{
+		Normalize:   true,
+		Planes:      8,
+		Tables:      4,
+		MaxDistance: 0.001,
+		Rand:        NewRandSource(1),
+	}
+	expected = []DuplicatePair{{0, 1}, {1, 0}}
+	if pairs := lsh.Leaks(train, validation); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("LSH: expected %v but got %v", expected, pairs)
+	}
+	cleaned := lsh.RemoveLeaks(train, validation)
+	if cleaned.Len() != 1 || !reflect.DeepEqual(cleaned.GetSample(0), train[2]) {
+		t.Errorf("unexpected cleaned set: %v", cleaned)
+	}
+}
+
+func TestDuplicatesNormalizeHasher(t *testing.T) {
+	samples := SliceSampleSet{
+		linalg.Vector{1, 2},
+		linalg.Vector{3, 4},
+		linalg.Vector{2, 4},
+	}
+	finder := &DuplicateFinder{Normalize: true}
+	expected := []DuplicatePair{{0, 2}}
+	if pairs := finder.Duplicates(samples); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v but got %v", expected, pairs)
+	}
+	hashed := VectorSet{Samples: samples}
+	if pairs := finder.Duplicates(hashed); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Hasher: expected %v but got %v", expected, pairs)
+	}
+}
+
+// collidingTestHasher is a Hasher which gives every sample
+// the same hash.
+type collidingTestHasher struct {
+	SliceSampleSet
+}
+
+func (c collidingTestHasher) Hash(i int) []byte {
+	return []byte("collision")
+}
+
+func TestDuplicatesHashCollision(t *testing.T) {
+	samples := collidingTestHasher{SliceSampleSet{
+		linalg.Vector{1, 2},
+		linalg.Vector{3, 4},
+		linalg.Vector{1, 2},
+	}}
+	finder := &DuplicateFinder{}
+	expected := []DuplicatePair{{0, 2}}
+	if pairs := finder.Duplicates(samples); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v but got %v", expected, pairs)
+	}
+	other := collidingTestHasher{SliceSampleSet{linalg.Vector{3, 4}}}
+	expected = []DuplicatePair{{1, 0}}
+	if pairs := finder.Leaks(samples, other); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("leaks: expected %v but got %v", expected, pairs)
+	}
+}
+
+func TestDuplicatesVectorPairs(t *testing.T) {
+	samples := []VectorPair{
+		{Input: linalg.Vector{1, 2}, Output: linalg.Vector{0}},
+		{Input: linalg.Vector{1, 2}, Output: linalg.Vector{1}},
+		{Input: linalg.Vector{1, 2}, Output: linalg.Vector{1}},
+	}
+	expected := []DuplicatePair{{1, 2}}
+	sets := map[string]SampleSet{
+		"PairSet":   PairSet{Samples: samples},
+		"InputOnly": PairSet{Samples: samples, InputOnly: true},
+		"Slice":     SliceSampleSet{samples[0], samples[1], samples[2]},
+	}
+	finders := map[string]*DuplicateFinder{
+		"exact":     {},
+		"quantized": {Quantum: 0.01, MaxDistance: 0.001},
+	}
+	for setName, set := range sets {
+		for finderName, finder := range finders {
+			if pairs := finder.Duplicates(set); !reflect.DeepEqual(pairs, expected) {
+				t.Errorf("%s, %s: expected %v but got %v", setName, finderName, expected,
+					pairs)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package sgd
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// ErrEarlyStop is returned by EarlyStopper when the
+// validation cost has stopped improving.
+var ErrEarlyStop = errors.New("validation cost stopped improving")
+
+// EarlyStopper is a Hook which periodically evaluates the
+// cost on a validation set and stops training once said
+// cost stops improving.
+//
+// When training stops for any reason, the parameters with
+// the best validation cost are restored.
+type EarlyStopper struct {
+	Coster     Coster
+	Learner    Learner
+	Validation SampleSet
+
+	// Interval is the number of steps between
+	// evaluations.
+	// If it is 0, the validation set is evaluated after
+	// each epoch instead.
+	Interval int
+
+	// Patience is the number of consecutive evaluations
+	// without improvement that are tolerated before
+	// training is stopped.
+	Patience int
+
+	// MinDelta is the amount by which the validation cost
+	// must decrease to count as an improvement.
+	// A NaN cost never counts as an improvement.
+	MinDelta float64
+
+	// BestCost and BestStep record the best validation
+	// cost seen so far and the step at which it was
+	// achieved.
+	// They are only meaningful after the first
+	// evaluation with a cost that is not NaN.
+	BestCost float64
+	BestStep int
+
+	best       []linalg.Vector
+	badEvals   int
+	evaluated  bool
+	stepsSince int
+}
+
+// HashSplitEarlyStopper splits a Hasher into training and
+// validation sets using HashSplit and creates an
+// EarlyStopper for the validation set.
+//
+// The validationRatio argument specifies the expected
+// fraction of samples used for validation.
+// As with HashSplit, h may be reordered.
+func HashSplitEarlyStopper(h Hasher, validationRatio float64, c Coster,
+	l Learner) (training SampleSet, e *EarlyStopper) {
+	training, validation := HashSplit(h, 1-validationRatio)
+	return training, &EarlyStopper{
+		Coster:     c,
+		Learner:    l,
+		Validation: validation,
+	}
+}
+
+// Evaluate computes the validation cost and updates the
+// best parameters if the cost improved.
+//
+// It returns ErrEarlyStop if Patience has run out.
+func (e *EarlyStopper) Evaluate(step int) error {
+	cost := e.Coster.Cost(e.Validation)
+	improved := !e.evaluated || cost < e.BestCost-e.MinDelta
+	if improved && !math.IsNaN(cost) {
+		e.evaluated = true
+		e.BestCost = cost
+		e.BestStep = step
+		e.best = snapshotParams(e.Learner.Parameters())
+		e.badEvals = 0
+		return nil
+	}
+	e.badEvals++
+	if e.badEvals > e.Patience {
+		return ErrEarlyStop
+	}
+	return nil
+}
+
+// Restore copies the best parameters seen so far into the
+// Learner.
+// It has no effect if no evaluations have been done.
+func (e *EarlyStopper) Restore() {
+	if e.best != nil {
+		restoreParams(e.Learner.Parameters(), e.best)
+	}
+}
+
+func (e *EarlyStopper) BeforeEpoch(info *StepInfo) error {
+	return nil
+}
+
+func (e *EarlyStopper) BeforeBatch(info *StepInfo) error {
+	return nil
+}
+
+func (e *EarlyStopper) AfterBatch(info *StepInfo) error {
+	if e.Interval == 0 || info.Gradient == nil {
+		return nil
+	}
+	e.stepsSince++
+	if e.stepsSince < e.Interval {
+		return nil
+	}
+	e.stepsSince = 0
+	return e.Evaluate(info.Step)
+}
+
+func (e *EarlyStopper) AfterEpoch(info *StepInfo) error {
+	if e.Interval != 0 {
+		return nil
+	}
+	return e.Evaluate(info.Step)
+}
+
+func (e *EarlyStopper) OnStop(info *StepInfo, reason error) {
+	e.Restore()
+}
+
+func snapshotParams(params []*autofunc.Variable) []linalg.Vector {
+	res := make([]linalg.Vector, len(params))
+	for i, p := range params {
+		res[i] = make(linalg.Vector, len(p.Vector))
+		copy(res[i], p.Vector)
+	}
+	return res
+}
+
+func restoreParams(params []*autofunc.Variable, vecs []linalg.Vector) {
+	for i, p := range params {
+		copy(p.Vector, vecs[i])
+	}
+}
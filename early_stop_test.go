@@ -0,0 +1,138 @@
+package sgd
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// scriptTestCoster returns a fixed sequence of costs.
+type scriptTestCoster struct {
+	Costs []float64
+	Calls int
+}
+
+func (s *scriptTestCoster) Cost(samples SampleSet) float64 {
+	res := s.Costs[s.Calls]
+	s.Calls++
+	return res
+}
+
+// distanceTestCoster computes the distance between a
+// variable's first component and a target.
+type distanceTestCoster struct {
+	Var    *autofunc.Variable
+	Target float64
+	Calls  int
+}
+
+func (d *distanceTestCoster) Cost(s SampleSet) float64 {
+	d.Calls++
+	return math.Abs(d.Var.Vector[0] - d.Target)
+}
+
+func (d *distanceTestCoster) Parameters() []*autofunc.Variable {
+	return []*autofunc.Variable{d.Var}
+}
+
+func TestEarlyStopperPatience(t *testing.T) {
+	coster := &scriptTestCoster{Costs: []float64{5, 4.95, 4, 3.99, 3.98}}
+	e := &EarlyStopper{
+		Coster:   coster,
+		Learner:  &distanceTestCoster{Var: &autofunc.Variable{Vector: []float64{0}}},
+		Patience: 1,
+		MinDelta: 0.1,
+	}
+	for step := range coster.Costs {
+		err := e.Evaluate(step)
+		if step < len(coster.Costs)-1 && err != nil {
+			t.Fatalf("step %d: unexpected error: %v", step, err)
+		} else if step == len(coster.Costs)-1 && err != ErrEarlyStop {
+			t.Fatalf("step %d: expected ErrEarlyStop but got %v", step, err)
+		}
+	}
+	if e.BestCost != 4 || e.BestStep != 2 {
+		t.Errorf("expected best cost 4 at step 2 but got %f at step %d",
+			e.BestCost, e.BestStep)
+	}
+}
+
+func TestEarlyStopperNaN(t *testing.T) {
+	coster := &scriptTestCoster{Costs: []float64{math.NaN(), 3, math.NaN(), 2}}
+	e := &EarlyStopper{
+		Coster:   coster,
+		Learner:  &distanceTestCoster{Var: &autofunc.Variable{Vector: []float64{0}}},
+		Patience: 10,
+	}
+	expected := []float64{0, 3, 3, 2}
+	for step, best := range expected {
+		if err := e.Evaluate(step); err != nil {
+			t.Fatal(err)
+		}
+		if e.BestCost != best {
+			t.Errorf("step %d: expected best cost %f but got %f", step, best, e.BestCost)
+		}
+	}
+	if e.BestStep != 3 {
+		t.Errorf("expected best step 3 but got %d", e.BestStep)
+	}
+}
+
+func TestEarlyStopperInterval(t *testing.T) {
+	for _, interval := range []int{0, 3} {
+		v := &autofunc.Variable{Vector: []float64{0}}
+		coster := &distanceTestCoster{Var: v, Target: 100}
+		trainer := &Trainer{
+			Gradienter: constTestGradienter{v: []float64{-1}},
+			Samples:    SliceSampleSet{1.0, 2.0, 3.0, 4.0},
+			BatchSize:  1,
+			Schedule:   ConstantSchedule(1),
+			Budget:     Budget{Epochs: 2},
+			Hooks: []Hook{&EarlyStopper{
+				Coster:   coster,
+				Learner:  coster,
+				Interval: interval,
+			}},
+		}
+		trainer.Run(context.Background())
+		expectedCalls := map[int]int{0: 2, 3: 2}[interval]
+		expectedBest := map[int]int{0: 8, 3: 6}[interval]
+		stopper := trainer.Hooks[0].(*EarlyStopper)
+		if coster.Calls != expectedCalls {
+			t.Errorf("interval %d: expected %d evaluations but got %d", interval,
+				expectedCalls, coster.Calls)
+		}
+		if stopper.BestStep != expectedBest {
+			t.Errorf("interval %d: expected best step %d but got %d", interval,
+				expectedBest, stopper.BestStep)
+		}
+	}
+}
+
+func TestEarlyStopperRestore(t *testing.T) {
+	v := &autofunc.Variable{Vector: []float64{0}}
+	coster := &distanceTestCoster{Var: v, Target: 3}
+	trainer := &Trainer{
+		Gradienter: constTestGradienter{v: []float64{-1}},
+		Samples:    SliceSampleSet{1.0, 2.0, 3.0, 4.0},
+		BatchSize:  1,
+		Schedule:   ConstantSchedule(1),
+		Hooks: []Hook{&EarlyStopper{
+			Coster:   coster,
+			Learner:  coster,
+			Interval: 1,
+			Patience: 2,
+		}},
+	}
+	if err := trainer.Run(context.Background()); err != ErrEarlyStop {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if trainer.Step != 6 {
+		t.Errorf("expected to stop after 6 steps but stopped after %d", trainer.Step)
+	}
+	if v.Vector[0] != 3 {
+		t.Errorf("expected restored value 3 but got %f", v.Vector[0])
+	}
+}
@@ -1,6 +1,9 @@
 package sgd
 
 import (
+	"encoding/binary"
+	"errors"
+	"io"
 	"math"
 	"math/rand"
 
@@ -58,6 +61,14 @@ type Equilibration struct {
 	// parameter has a very small row in the Hessian.
 	Damping float64
 
+	// Rand, if non-nil, is used to sample the random
+	// vectors for the Hessian estimates.
+	// Otherwise, the global source from math/rand is used.
+	//
+	// Since Rand is saved by SaveState, an Equilibration
+	// with a Rand can be checkpointed and resumed exactly.
+	Rand *RandSource
+
 	lastUpdate int
 	rCache     autofunc.RVector
 	squareMags autofunc.RGradient
@@ -86,6 +97,61 @@ func (e *Equilibration) Gradient(s SampleSet) autofunc.Gradient {
 	return rawGrad
 }
 
+// SaveState saves the normalization coefficients, the
+// number of Gradient calls since they were last updated,
+// and the state of Rand.
+func (e *Equilibration) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	if err := writeStateHeader(w, "Equilibration"); err != nil {
+		return err
+	}
+	if err := writeStateInt(w, e.lastUpdate); err != nil {
+		return err
+	}
+	if err := writeStateBool(w, e.Rand != nil); err != nil {
+		return err
+	}
+	if e.Rand != nil {
+		if err := binary.Write(w, stateByteOrder, e.Rand.State()); err != nil {
+			return err
+		}
+	}
+	return writeStateGradient(w, e.squareMags, params)
+}
+
+// LoadState loads state saved with SaveState.
+func (e *Equilibration) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	if err := readStateHeader(r, "Equilibration"); err != nil {
+		return err
+	}
+	lastUpdate, err := readStateInt(r)
+	if err != nil {
+		return err
+	}
+	hasRand, err := readStateBool(r)
+	if err != nil {
+		return err
+	}
+	var randState uint64
+	if hasRand {
+		if err := binary.Read(r, stateByteOrder, &randState); err != nil {
+			return err
+		}
+		if e.Rand == nil {
+			return errors.New("state requires an Equilibration with a Rand")
+		}
+	}
+	mags, err := readStateGradient(r, params)
+	if err != nil {
+		return err
+	}
+	e.lastUpdate = lastUpdate
+	e.squareMags = mags
+	if hasRand {
+		e.Rand.SetState(randState)
+	}
+	return nil
+}
+
 func (e *Equilibration) updateSquareMags(s SampleSet) autofunc.Gradient {
 	if e.rCache == nil {
 		params := e.Learner.Parameters()
@@ -137,9 +203,15 @@ func (e *Equilibration) updateSquareMags(s SampleSet) autofunc.Gradient {
 }
 
 func (e *Equilibration) randomizeRVector() {
-	for _, vec := range e.rCache {
+	normFloat := rand.NormFloat64
+	if e.Rand != nil {
+		normFloat = rand.New(e.Rand).NormFloat64
+	}
+	params := e.Learner.Parameters()
+	for _, p := range params {
+		vec := e.rCache[p]
 		for i := range vec {
-			vec[i] = rand.NormFloat64()
+			vec[i] = normFloat()
 		}
 	}
 }
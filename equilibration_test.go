@@ -82,3 +82,50 @@ func TestEquilibrationMemory(t *testing.T) {
 		}
 	}
 }
+
+// rVectorTestGradienter records the r-vectors it is given.
+type rVectorTestGradienter struct {
+	Var      *autofunc.Variable
+	RVectors [][]float64
+}
+
+func (r *rVectorTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	return autofunc.NewGradient(r.Parameters())
+}
+
+func (r *rVectorTestGradienter) RGradient(rv autofunc.RVector,
+	s SampleSet) (autofunc.Gradient, autofunc.RGradient) {
+	r.RVectors = append(r.RVectors, append([]float64{}, rv[r.Var]...))
+	return r.Gradient(s), autofunc.NewRGradient(r.Parameters())
+}
+
+func (r *rVectorTestGradienter) Parameters() []*autofunc.Variable {
+	return []*autofunc.Variable{r.Var}
+}
+
+func TestEquilibrationSeed(t *testing.T) {
+	rVectors := func(seed int64) [][]float64 {
+		g := &rVectorTestGradienter{Var: &autofunc.Variable{Vector: []float64{1, 2, 3}}}
+		eq := &Equilibration{
+			RGradienter: g,
+			Learner:     g,
+			NumSamples:  3,
+			Rand:        NewRandSource(seed),
+		}
+		for i := 0; i < 4; i++ {
+			eq.Gradient(SliceSampleSet{1.0})
+		}
+		return g.RVectors
+	}
+	first := rVectors(1337)
+	if len(first) != 12 {
+		t.Fatalf("expected 12 r-vectors but got %d", len(first))
+	}
+	for i, vec := range rVectors(1337) {
+		for j, x := range vec {
+			if x != first[i][j] {
+				t.Fatalf("r-vector %d: expected %v but got %v", i, first[i], vec)
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package sgd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// An Event is a record of something which happened during
+// training.
+type Event interface {
+	// EventType returns a short name for the kind of
+	// event, such as "step".
+	EventType() string
+}
+
+// An EventSink receives events.
+//
+// Sinks must be safe to use from multiple Goroutines.
+type EventSink interface {
+	Emit(e Event)
+}
+
+// A StepEvent is emitted for every parameter update.
+//
+// For statistics about the individual variables, wrap the
+// Gradienter in a Debugger.
+type StepEvent struct {
+	Step      int           `json:"step"`
+	Epoch     int           `json:"epoch"`
+	StepSize  float64       `json:"step_size"`
+	BatchSize int           `json:"batch_size"`
+	Duration  time.Duration `json:"duration"`
+
+	// GradientNorm is the Euclidean norm of the gradient
+	// used for the update, i.e. after it has gone through
+	// any Transformers.
+	GradientNorm float64 `json:"gradient_norm"`
+}
+
+func (s *StepEvent) EventType() string {
+	return "step"
+}
+
+// An EpochEvent is emitted at the end of every epoch.
+type EpochEvent struct {
+	Epoch    int           `json:"epoch"`
+	Step     int           `json:"step"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (e *EpochEvent) EventType() string {
+	return "epoch"
+}
+
+// A StopEvent is emitted when a Trainer stops.
+type StopEvent struct {
+	Epoch int `json:"epoch"`
+	Step  int `json:"step"`
+
+	// Reason is the message of the error returned by
+	// the Trainer.
+	Reason string `json:"reason"`
+}
+
+func (s *StopEvent) EventType() string {
+	return "stop"
+}
+
+// An InterruptEvent is emitted when an os.Interrupt is
+// caught.
+type InterruptEvent struct{}
+
+func (i *InterruptEvent) EventType() string {
+	return "interrupt"
+}
+
+// A GradientEvent summarizes all the entries of a
+// gradient.
+type GradientEvent struct {
+	// Call is the number of gradients computed by the
+	// Debugger so far, including this one.
+	// This is not necessarily the same as a Trainer's
+	// step, since a step may involve several gradients
+	// (e.g. with an Accumulator or a line search).
+	Call int `json:"call"`
+
+	Norm     float64 `json:"norm"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+}
+
+func (g *GradientEvent) EventType() string {
+	return "gradient"
+}
+
+// A VariableEvent summarizes the gradient for a single
+// variable.
+type VariableEvent struct {
+	// Call is the same as for GradientEvent.
+	Call int `json:"call"`
+
+	// Index is the index of the variable in the list of
+	// parameters.
+	Index int `json:"index"`
+
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	MaxAbs   float64 `json:"max_abs"`
+
+	// GradOverVal is the mean absolute ratio between the
+	// gradient entries and the variable's entries.
+	GradOverVal float64 `json:"grad_over_val"`
+
+	Zeroes int `json:"zeroes"`
+}
+
+func (v *VariableEvent) EventType() string {
+	return "variable"
+}
+
+// JSONSink is an EventSink which writes each event as a
+// line of JSON.
+//
+// Each line is an object with a "type" field (from
+// EventType), a "time" field, and the fields of the
+// event itself.
+type JSONSink struct {
+	lock   sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	err    error
+}
+
+// NewJSONSink creates a JSONSink which writes to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// CreateJSONSink creates (or truncates) a file and returns
+// a JSONSink which writes to it.
+// The sink should be closed when it is no longer needed.
+func CreateJSONSink(path string) (*JSONSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONSink{w: f, closer: f}, nil
+}
+
+// Emit writes the event.
+//
+// If encoding or writing fails, the event is dropped and
+// the error is recorded for Err.
+func (j *JSONSink) Emit(e Event) {
+	header, err := json.Marshal(struct {
+		Type string    `json:"type"`
+		Time time.Time `json:"time"`
+	}{e.EventType(), time.Now()})
+	if err != nil {
+		j.setErr(err)
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		j.setErr(err)
+		return
+	}
+
+	// Splice the event's fields into the header object.
+	line := header[:len(header)-1]
+	if len(body) > 2 {
+		line = append(line, ',')
+		line = append(line, body[1:]...)
+	} else {
+		line = append(line, '}')
+	}
+	line = append(line, '\n')
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if _, err := j.w.Write(line); err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// Err returns the first error encountered while emitting
+// events, or nil if there was none.
+func (j *JSONSink) Err() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.err
+}
+
+// Close closes the underlying file, if the sink was
+// created with CreateJSONSink.
+// It returns the first error encountered by the sink, if
+// there was one.
+func (j *JSONSink) Close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closer != nil {
+		if err := j.closer.Close(); err != nil && j.err == nil {
+			j.err = err
+		}
+		j.closer = nil
+	}
+	return j.err
+}
+
+func (j *JSONSink) setErr(err error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.err == nil {
+		j.err = err
+	}
+}
+
+// MemorySink is an EventSink which stores events in
+// memory.
+// It is mainly useful for testing.
+type MemorySink struct {
+	lock   sync.Mutex
+	events []Event
+}
+
+// Emit records the event.
+func (m *MemorySink) Emit(e Event) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.events = append(m.events, e)
+}
+
+// Events returns a copy of the events emitted so far.
+func (m *MemorySink) Events() []Event {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]Event{}, m.events...)
+}
@@ -0,0 +1,78 @@
+package sgd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/autofunc"
+)
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONSink(&buf)
+	sink.Emit(&StepEvent{Step: 3, Epoch: 1, StepSize: 0.5, BatchSize: 10,
+		Duration: time.Second, GradientNorm: 2})
+	sink.Emit(&EpochEvent{Epoch: 2, Step: 7})
+	if err := sink.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines but got %d", len(lines))
+	}
+	var step map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &step); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"type":          "step",
+		"step":          3.0,
+		"epoch":         1.0,
+		"step_size":     0.5,
+		"batch_size":    10.0,
+		"duration":      float64(time.Second),
+		"gradient_norm": 2.0,
+	}
+	for key, x := range expected {
+		if a := step[key]; a != x {
+			t.Errorf("field %s: expected %v but got %v", key, x, a)
+		}
+	}
+	if _, ok := step["time"]; !ok {
+		t.Error("missing time field")
+	}
+}
+
+func TestTrainerStepEvents(t *testing.T) {
+	sink := &MemorySink{}
+	v := &autofunc.Variable{Vector: []float64{0, 0}}
+	trainer := &Trainer{
+		Gradienter: constTestGradienter{v: {3, 4}},
+		Samples:    SliceSampleSet{1.0, 2.0, 3.0},
+		BatchSize:  2,
+		Schedule:   ConstantSchedule(0.1),
+		Budget:     Budget{Steps: 2},
+		Events:     sink,
+	}
+	if err := trainer.Run(context.Background()); err != ErrStepLimit {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var steps int
+	for _, e := range sink.Events() {
+		if step, ok := e.(*StepEvent); ok {
+			steps++
+			if step.StepSize != 0.1 || math.Abs(step.GradientNorm-5) > 1e-8 {
+				t.Errorf("unexpected step event: %+v", step)
+			}
+		}
+	}
+	if steps != 2 {
+		t.Errorf("expected 2 step events but got %d", steps)
+	}
+}
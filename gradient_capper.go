@@ -1,6 +1,10 @@
 package sgd
 
-import "github.com/unixpickle/autofunc"
+import (
+	"io"
+
+	"github.com/unixpickle/autofunc"
+)
 
 // GradientCapper is a Gradienter which caps individual
 // components of the gradient to a certain maximum
@@ -30,3 +34,14 @@ func (g *GradientCapper) Transform(res autofunc.Gradient) autofunc.Gradient {
 	}
 	return res
 }
+
+// SaveState writes an empty state, since capping only
+// depends on Cap.
+func (g *GradientCapper) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return saveStateless(w, "GradientCapper")
+}
+
+// LoadState reads a state saved with SaveState.
+func (g *GradientCapper) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return loadStateless(r, "GradientCapper")
+}
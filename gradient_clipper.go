@@ -1,6 +1,7 @@
 package sgd
 
 import (
+	"io"
 	"math"
 
 	"github.com/unixpickle/autofunc"
@@ -50,3 +51,14 @@ func (c *GradientClipper) Transform(res autofunc.Gradient) autofunc.Gradient {
 	}
 	return res
 }
+
+// SaveState writes an empty state, since every gradient
+// is clipped on its own.
+func (c *GradientClipper) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	return saveStateless(w, "GradientClipper")
+}
+
+// LoadState reads a state saved with SaveState.
+func (c *GradientClipper) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	return loadStateless(r, "GradientClipper")
+}
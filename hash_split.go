@@ -1,7 +1,6 @@
 package sgd
 
 import (
-	"bytes"
 	"crypto/md5"
 	"encoding/binary"
 	"io"
@@ -23,34 +22,7 @@ type Hasher interface {
 // It takes vectors and hashes them in a
 // randomly-distributed manner.
 func HashVectors(vecs ...linalg.Vector) []byte {
-	var buf bytes.Buffer
-	tempBuf := make([]byte, 8)
-
-	var lastValue float64
-	var valueCount byte
-	for _, vec := range vecs {
-		for _, x := range vec {
-			if x == lastValue && valueCount < 0xff {
-				valueCount++
-			} else {
-				if valueCount > 0 {
-					buf.WriteByte(valueCount)
-					writeFloatBits(&buf, tempBuf, lastValue)
-				}
-				lastValue = x
-				valueCount = 1
-			}
-		}
-		if valueCount > 0 {
-			buf.WriteByte(valueCount)
-			writeFloatBits(&buf, tempBuf, lastValue)
-			valueCount = 0
-		}
-		// Separator between vectors.
-		buf.WriteByte(0)
-	}
-	res := md5.Sum(buf.Bytes())
-	return res[:]
+	return (*HashOptions)(nil).HashVectors(vecs...)
 }
 
 // HashSplit partitions a Hasher.
@@ -121,3 +93,155 @@ func writeFloatBits(w io.Writer, temp []byte, val float64) {
 	binary.BigEndian.PutUint64(temp, math.Float64bits(val))
 	w.Write(temp)
 }
+
+// HashSplitN partitions a Hasher into len(ratios) parts.
+//
+// Each ratio specifies the expected fraction of samples
+// in the corresponding part.
+// The ratios are normalized so that they sum to 1.
+// HashSplitN panics if any ratio is negative or if all of
+// the ratios are zero.
+//
+// Like HashSplit, HashSplitN may reorder h, and the part
+// a sample ends up in depends only on its hash and the
+// ratios, so HashSplitN(h, r, 1-r) is equivalent to
+// HashSplit(h, r).
+func HashSplitN(h Hasher, ratios ...float64) []SampleSet {
+	if len(ratios) == 0 {
+		panic("at least one ratio is required")
+	}
+	var total float64
+	for _, r := range ratios {
+		if !(r >= 0) {
+			panic("ratios must be non-negative")
+		}
+		total += r
+	}
+	if total == 0 {
+		panic("at least one ratio must be positive")
+	}
+
+	// cutoffs[i] is the lower bound for the hashes in
+	// part i+1, or nil if part i is the last non-empty
+	// part.
+	cutoffs := make([][]byte, len(ratios)-1)
+	var cumulative float64
+	for i := range cutoffs {
+		cumulative += ratios[i] / total
+		if cumulative >= 1 {
+			break
+		}
+		cutoffs[i] = hashCutoff(cumulative)
+	}
+	partIdx := func(hash []byte) int {
+		for i, cutoff := range cutoffs {
+			if cutoff == nil || compareHashes(hash, cutoff) < 0 {
+				return i
+			}
+		}
+		return len(cutoffs)
+	}
+
+	parts := make([]int, h.Len())
+	for i := range parts {
+		parts[i] = partIdx(h.Hash(i))
+	}
+	res := make([]SampleSet, len(ratios))
+	start := 0
+	for part := range res {
+		insertIdx := start
+		for i := start; i < len(parts); i++ {
+			if parts[i] == part {
+				h.Swap(insertIdx, i)
+				parts[insertIdx], parts[i] = parts[i], parts[insertIdx]
+				insertIdx++
+			}
+		}
+		res[part] = h.Subset(start, insertIdx)
+		start = insertIdx
+	}
+	return res
+}
+
+// A Fold is one round of K-fold cross-validation.
+type Fold struct {
+	Train      SampleSet
+	Validation SampleSet
+}
+
+// KFold splits a Hasher into k parts of (roughly) equal
+// size using HashSplitN, and returns k folds which use
+// each part for validation and the rest for training.
+//
+// The training set of each fold is created with
+// ConcatSampleSets, so it may be reordered independently
+// of the other folds.
+// Like HashSplitN, KFold may reorder h.
+func KFold(h Hasher, k int) []Fold {
+	if k < 2 {
+		panic("k must be at least 2")
+	}
+	ratios := make([]float64, k)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	parts := HashSplitN(h, ratios...)
+	res := make([]Fold, k)
+	for i, part := range parts {
+		var train []SampleSet
+		for j, other := range parts {
+			if j != i {
+				train = append(train, other)
+			}
+		}
+		res[i] = Fold{Train: ConcatSampleSets(train...), Validation: part}
+	}
+	return res
+}
+
+// SaltedHasher is a Hasher which mixes a salt into the
+// hashes of another Hasher.
+//
+// Splitting a SaltedHasher partitions the samples
+// differently for every salt, but consistently for any
+// given salt.
+// This makes it possible to draw several independent
+// splits from the same data.
+// It works on top of any Hasher, regardless of the hash
+// function that the Hasher uses.
+//
+// The Copy and Subset methods of the wrapped Hasher must
+// return Hashers.
+type SaltedHasher struct {
+	Hasher Hasher
+	Salt   []byte
+}
+
+func (s *SaltedHasher) Len() int {
+	return s.Hasher.Len()
+}
+
+func (s *SaltedHasher) Copy() SampleSet {
+	return &SaltedHasher{Hasher: s.Hasher.Copy().(Hasher), Salt: s.Salt}
+}
+
+func (s *SaltedHasher) Swap(i, j int) {
+	s.Hasher.Swap(i, j)
+}
+
+func (s *SaltedHasher) GetSample(idx int) interface{} {
+	return s.Hasher.GetSample(idx)
+}
+
+func (s *SaltedHasher) Subset(start, end int) SampleSet {
+	return &SaltedHasher{Hasher: s.Hasher.Subset(start, end).(Hasher), Salt: s.Salt}
+}
+
+// Hash computes the MD5 hash of the salt followed by the
+// wrapped Hasher's hash of the sample.
+func (s *SaltedHasher) Hash(idx int) []byte {
+	h := md5.New()
+	h.Write(s.Salt)
+	h.Write(s.Hasher.Hash(idx))
+	return h.Sum(nil)
+}
@@ -2,6 +2,8 @@ package sgd
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"reflect"
 	"testing"
 
 	"github.com/unixpickle/num-analysis/linalg"
@@ -67,3 +69,131 @@ func TestHashVectors(t *testing.T) {
 		t.Error("hash collision")
 	}
 }
+
+func TestHashSplitN(t *testing.T) {
+	var samples byteHashSet
+	for i := 0; i < 256; i++ {
+		samples = append(samples, []byte{byte(i)})
+	}
+	parts := HashSplitN(samples, 1, 2, 0, 1)
+	expected := []int{64, 128, 0, 64}
+	for i, part := range parts {
+		if part.Len() != expected[i] {
+			t.Errorf("part %d: expected %d samples but got %d", i, expected[i], part.Len())
+		}
+		lower := byte(0)
+		if i > 0 {
+			lower = byte(64 * (i - 1))
+		}
+		for j := 0; j < part.Len(); j++ {
+			if x := part.GetSample(j).([]byte)[0]; x < lower {
+				t.Errorf("part %d: unexpected sample %d", i, x)
+			}
+		}
+	}
+
+	left, right := HashSplit(samples.Copy().(Hasher), 0.3)
+	parts = HashSplitN(samples, 0.3, 0.7)
+	if left.Len() != parts[0].Len() || right.Len() != parts[1].Len() {
+		t.Error("HashSplitN disagrees with HashSplit")
+	}
+}
+
+func TestHashSplitNInvalid(t *testing.T) {
+	samples := byteHashSet{{0x10}, {0x80}}
+	for _, ratios := range [][]float64{{}, {0, 0}, {1, -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ratios %v: expected panic", ratios)
+				}
+			}()
+			HashSplitN(samples, ratios...)
+		}()
+	}
+}
+
+func TestKFold(t *testing.T) {
+	var samples byteHashSet
+	for i := 0; i < 100; i++ {
+		samples = append(samples, HashVectors(linalg.Vector{float64(i)}))
+	}
+	folds := KFold(samples, 4)
+	seen := map[string]int{}
+	for i, fold := range folds {
+		if fold.Train.Len()+fold.Validation.Len() != samples.Len() {
+			t.Errorf("fold %d: bad sizes %d, %d", i, fold.Train.Len(), fold.Validation.Len())
+		}
+		inValidation := map[string]bool{}
+		for j := 0; j < fold.Validation.Len(); j++ {
+			key := string(fold.Validation.GetSample(j).([]byte))
+			inValidation[key] = true
+			seen[key]++
+		}
+		for j := 0; j < fold.Train.Len(); j++ {
+			if inValidation[string(fold.Train.GetSample(j).([]byte))] {
+				t.Errorf("fold %d: sample in training and validation", i)
+			}
+		}
+	}
+	if len(seen) != samples.Len() {
+		t.Errorf("expected %d validation samples but got %d", samples.Len(), len(seen))
+	}
+	for _, count := range seen {
+		if count != 1 {
+			t.Error("sample validated more than once")
+		}
+	}
+}
+
+func TestSaltedHasher(t *testing.T) {
+	var samples byteHashSet
+	for i := 0; i < 100; i++ {
+		samples = append(samples, HashVectors(linalg.Vector{float64(i)}))
+	}
+	leftSet := func(salt string) map[string]bool {
+		h := &SaltedHasher{Hasher: samples.Copy().(Hasher), Salt: []byte(salt)}
+		left, _ := HashSplit(h, 0.5)
+		res := map[string]bool{}
+		for i := 0; i < left.Len(); i++ {
+			res[string(left.GetSample(i).([]byte))] = true
+		}
+		return res
+	}
+	if !reflect.DeepEqual(leftSet("a"), leftSet("a")) {
+		t.Error("same salt gave different splits")
+	}
+	if reflect.DeepEqual(leftSet("a"), leftSet("b")) {
+		t.Error("different salts gave the same split")
+	}
+}
+
+func TestHasherSets(t *testing.T) {
+	vecs := []linalg.Vector{{1, 2, 3}, {3, 3, 0}}
+	pair := VectorPair{Input: vecs[0], Output: vecs[1]}
+
+	if !bytes.Equal((VectorSet{Samples: SliceSampleSet{vecs}}).Hash(0), HashVectors(vecs...)) {
+		t.Error("VectorSet hash mismatch")
+	}
+	pairs := PairSet{Samples: []VectorPair{pair}}
+	if !bytes.Equal(pairs.Hash(0), HashVectors(vecs...)) {
+		t.Error("PairSet hash mismatch")
+	}
+	pairs.InputOnly = true
+	if !bytes.Equal(pairs.Hash(0), HashVectors(vecs[0])) {
+		t.Error("PairSet input-only hash mismatch")
+	}
+
+	strs := BytesSet{Samples: SliceSampleSet{"hello", []byte("hello")}}
+	if !bytes.Equal(strs.Hash(0), strs.Hash(1)) {
+		t.Error("string and []byte hashes differ")
+	}
+	h := sha256.Sum256([]byte("hello"))
+	strs.Options = &HashOptions{New: sha256.New}
+	if !bytes.Equal(strs.Hash(0), h[:]) {
+		t.Error("custom hash mismatch")
+	}
+	if _, ok := strs.Subset(0, 1).(BytesSet); !ok {
+		t.Error("subset should be a BytesSet")
+	}
+}
@@ -0,0 +1,211 @@
+package sgd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"hash"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// HashOptions specifies how to hash samples.
+//
+// A nil *HashOptions, or the zero value, uses MD5, which is
+// what HashVectors uses.
+//
+// To split the same samples in several different ways,
+// wrap a Hasher in a SaltedHasher.
+type HashOptions struct {
+	// New creates the underlying hash function, such as
+	// md5.New, sha256.New, or fnv.New128a.
+	// If it is nil, md5.New is used.
+	New func() hash.Hash
+}
+
+// HashBytes hashes raw data.
+func (h *HashOptions) HashBytes(data []byte) []byte {
+	if h == nil || h.New == nil {
+		res := md5.Sum(data)
+		return res[:]
+	}
+	hasher := h.New()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+// HashVectors hashes a list of vectors.
+// With the default options, it is equivalent to the
+// HashVectors function.
+func (h *HashOptions) HashVectors(vecs ...linalg.Vector) []byte {
+	var buf bytes.Buffer
+	tempBuf := make([]byte, 8)
+
+	var lastValue float64
+	var valueCount byte
+	for _, vec := range vecs {
+		for _, x := range vec {
+			if x == lastValue && valueCount < 0xff {
+				valueCount++
+			} else {
+				if valueCount > 0 {
+					buf.WriteByte(valueCount)
+					writeFloatBits(&buf, tempBuf, lastValue)
+				}
+				lastValue = x
+				valueCount = 1
+			}
+		}
+		if valueCount > 0 {
+			buf.WriteByte(valueCount)
+			writeFloatBits(&buf, tempBuf, lastValue)
+			valueCount = 0
+		}
+		// Separator between vectors.
+		buf.WriteByte(0)
+	}
+	return h.HashBytes(buf.Bytes())
+}
+
+// VectorSet is a Hasher whose samples are linalg.Vectors
+// or []linalg.Vectors.
+type VectorSet struct {
+	Samples SliceSampleSet
+
+	// Options specifies how samples are hashed.
+	// If it is nil, the default options are used.
+	Options *HashOptions
+}
+
+func (v VectorSet) Len() int {
+	return len(v.Samples)
+}
+
+func (v VectorSet) Copy() SampleSet {
+	return VectorSet{Samples: v.Samples.Copy().(SliceSampleSet), Options: v.Options}
+}
+
+func (v VectorSet) Swap(i, j int) {
+	v.Samples.Swap(i, j)
+}
+
+func (v VectorSet) GetSample(idx int) interface{} {
+	return v.Samples[idx]
+}
+
+func (v VectorSet) Subset(start, end int) SampleSet {
+	return VectorSet{Samples: v.Samples[start:end], Options: v.Options}
+}
+
+// Hash hashes the sample at the given index.
+// It panics if the sample is not a linalg.Vector or a
+// []linalg.Vector.
+func (v VectorSet) Hash(idx int) []byte {
+	switch sample := v.Samples[idx].(type) {
+	case linalg.Vector:
+		return v.Options.HashVectors(sample)
+	case []linalg.Vector:
+		return v.Options.HashVectors(sample...)
+	default:
+		panic("unsupported sample type")
+	}
+}
+
+// A VectorPair is a training sample with an input and a
+// desired output.
+type VectorPair struct {
+	Input  linalg.Vector
+	Output linalg.Vector
+}
+
+// PairSet is a Hasher whose samples are VectorPairs.
+type PairSet struct {
+	Samples []VectorPair
+
+	// Options specifies how samples are hashed.
+	// If it is nil, the default options are used.
+	Options *HashOptions
+
+	// InputOnly, if true, causes samples to be hashed
+	// solely by their inputs, so that samples with the
+	// same input always end up on the same side of a
+	// split.
+	InputOnly bool
+}
+
+func (p PairSet) Len() int {
+	return len(p.Samples)
+}
+
+func (p PairSet) Copy() SampleSet {
+	res := p
+	res.Samples = append([]VectorPair{}, p.Samples...)
+	return res
+}
+
+func (p PairSet) Swap(i, j int) {
+	p.Samples[i], p.Samples[j] = p.Samples[j], p.Samples[i]
+}
+
+func (p PairSet) GetSample(idx int) interface{} {
+	return p.Samples[idx]
+}
+
+func (p PairSet) Subset(start, end int) SampleSet {
+	res := p
+	res.Samples = p.Samples[start:end]
+	return res
+}
+
+// Hash hashes the sample at the given index.
+// Unless InputOnly is set, this is equivalent to hashing
+// the input and output with HashVectors.
+func (p PairSet) Hash(idx int) []byte {
+	sample := p.Samples[idx]
+	if p.InputOnly {
+		return p.Options.HashVectors(sample.Input)
+	}
+	return p.Options.HashVectors(sample.Input, sample.Output)
+}
+
+// BytesSet is a Hasher whose samples are []bytes or
+// strings.
+type BytesSet struct {
+	Samples SliceSampleSet
+
+	// Options specifies how samples are hashed.
+	// If it is nil, the default options are used.
+	Options *HashOptions
+}
+
+func (b BytesSet) Len() int {
+	return len(b.Samples)
+}
+
+func (b BytesSet) Copy() SampleSet {
+	return BytesSet{Samples: b.Samples.Copy().(SliceSampleSet), Options: b.Options}
+}
+
+func (b BytesSet) Swap(i, j int) {
+	b.Samples.Swap(i, j)
+}
+
+func (b BytesSet) GetSample(idx int) interface{} {
+	return b.Samples[idx]
+}
+
+func (b BytesSet) Subset(start, end int) SampleSet {
+	return BytesSet{Samples: b.Samples[start:end], Options: b.Options}
+}
+
+// Hash hashes the sample at the given index.
+// It panics if the sample is not a []byte or a string.
+func (b BytesSet) Hash(idx int) []byte {
+	switch sample := b.Samples[idx].(type) {
+	case []byte:
+		return b.Options.HashBytes(sample)
+	case string:
+		return b.Options.HashBytes([]byte(sample))
+	default:
+		panic("unsupported sample type")
+	}
+}
@@ -0,0 +1,276 @@
+package sgd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+)
+
+const (
+	hessianFreeDefaultMaxIters      = 100
+	hessianFreeDefaultGradTolerance = 1e-5
+	hessianFreeDefaultDamping       = 1
+	hessianFreeDefaultCGIters       = 50
+	hessianFreeDefaultCGTolerance   = 1e-4
+	hessianFreeMaxRejections        = 30
+)
+
+// HessianFree implements a Hessian-free (truncated
+// Newton) optimizer, as described in
+// http://www.cs.toronto.edu/~jmartens/docs/Deep_HessianFree.pdf.
+//
+// Each step approximately minimizes a quadratic model of
+// the cost using conjugate gradients, with the curvature
+// given by the R-gradients of RGradienter.
+// For a true Newton step, RGradienter should compute
+// Hessian-vector products.
+// An RGradienter which computes Gauss-Newton-vector
+// products can be used instead, which is often more
+// robust for non-convex problems.
+//
+// The curvature is damped by adding a multiple of the
+// identity to it, and the damping is adjusted after each
+// step with the Levenberg-Marquardt heuristic: it is
+// increased when the quadratic model predicts the change
+// in cost poorly, and decreased when it predicts it well.
+type HessianFree struct {
+	RGradienter RGradienter
+	Coster      Coster
+
+	// Damping is the initial damping coefficient.
+	// If it is 0, a default of 1 is used.
+	Damping float64
+
+	// CGIters is the maximum number of conjugate gradient
+	// iterations for each step.
+	// If it is 0, a default of 50 is used.
+	CGIters int
+
+	// CGTolerance is the size of the CG residual, relative
+	// to the size of the gradient, at which CG terminates.
+	// If it is 0, a default of 1e-4 is used.
+	CGTolerance float64
+
+	// PreconditionSamples is the number of random vectors
+	// used to estimate the diagonal preconditioner before
+	// each step, using the same estimate of the magnitudes
+	// of the Hessian's rows as Equilibration.
+	// If it is 0, CG is not preconditioned.
+	PreconditionSamples int
+
+	// Learner is used to sample the preconditioner's
+	// random vectors in a consistent order.
+	// It is required if PreconditionSamples is set.
+	Learner Learner
+
+	// Rand, if non-nil, is used to sample the random
+	// vectors for the preconditioner.
+	// Otherwise, the global source from math/rand is used.
+	Rand *RandSource
+
+	// MaxIters is the maximum number of steps.
+	// If it is 0, a default of 100 is used.
+	MaxIters int
+
+	// GradTolerance is the largest absolute gradient
+	// component at which the optimization is considered
+	// converged.
+	// If it is 0, a default of 1e-5 is used.
+	GradTolerance float64
+
+	// CostTolerance, if non-zero, stops the optimization
+	// when a step decreases the cost by less than this
+	// fraction of the cost's magnitude.
+	CostTolerance float64
+}
+
+// Minimize runs the optimizer on the samples until one of
+// the stopping conditions is met, or until ctx is done.
+//
+// Steps which increase the cost are undone and retried
+// with more damping, and do not count as iterations.
+// If too many steps are rejected in a row, the reason is
+// LineSearchFailed.
+//
+// If the context is done, the result is returned along
+// with the context's error.
+// If PreconditionSamples is set without a Learner, an
+// error is returned before any work is done.
+func (h *HessianFree) Minimize(ctx context.Context, s SampleSet) (*OptimizerResult, error) {
+	if h.PreconditionSamples > 0 && h.Learner == nil {
+		return nil, errors.New("preconditioning requires a Learner")
+	}
+	maxIters := defaultInt(h.MaxIters, hessianFreeDefaultMaxIters)
+	gradTol := defaultFloat(h.GradTolerance, hessianFreeDefaultGradTolerance)
+	damping := defaultFloat(h.Damping, hessianFreeDefaultDamping)
+
+	cost := h.Coster.Cost(s)
+	grad := mustGradient(h.RGradienter, s).Copy()
+	res := &OptimizerResult{Cost: cost}
+	var rejections int
+
+	for {
+		if gradientMaxAbs(grad) <= gradTol {
+			res.Reason = GradientConverged
+			return res, nil
+		}
+		if res.Iterations >= maxIters {
+			res.Reason = IterationLimit
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			res.Reason = Canceled
+			return res, ctx.Err()
+		default:
+		}
+
+		step := h.solve(s, grad, damping)
+		predicted := gradientDot(grad, step) +
+			0.5*gradientDot(step, h.curvatureProduct(s, step))
+		step.AddToVars(1)
+		newCost := h.Coster.Cost(s)
+
+		// Levenberg-Marquardt adjustment, with the
+		// constants suggested by Martens.
+		if predicted < 0 {
+			ratio := (newCost - cost) / predicted
+			if ratio < 0.25 {
+				damping *= 1.5
+			} else if ratio > 0.75 {
+				damping *= 2.0 / 3
+			}
+		} else {
+			damping *= 1.5
+		}
+
+		if !(newCost < cost) {
+			step.AddToVars(-1)
+			rejections++
+			if rejections >= hessianFreeMaxRejections {
+				res.Reason = LineSearchFailed
+				return res, nil
+			}
+			continue
+		}
+		rejections = 0
+
+		res.Iterations++
+		res.Cost = newCost
+		decrease := cost - newCost
+		cost = newCost
+		grad = mustGradient(h.RGradienter, s).Copy()
+		if h.CostTolerance != 0 && decrease <= h.CostTolerance*math.Abs(cost) {
+			res.Reason = CostConverged
+			return res, nil
+		}
+	}
+}
+
+// solve runs preconditioned CG to approximately solve
+// (B + damping*I)*x = -grad, where B is the curvature.
+//
+// If CG encounters non-positive curvature, it stops and
+// returns the solution so far, or a steepest descent
+// step (scaled down by the damping or the preconditioner)
+// if it has not made any progress.
+func (h *HessianFree) solve(s SampleSet, grad autofunc.Gradient,
+	damping float64) autofunc.Gradient {
+	iters := defaultInt(h.CGIters, hessianFreeDefaultCGIters)
+	tol := defaultFloat(h.CGTolerance, hessianFreeDefaultCGTolerance) *
+		math.Sqrt(gradientDot(grad, grad))
+	precond := h.preconditioner(s, grad, damping)
+
+	solution := scaledGradient(grad, 0)
+	residual := scaledGradient(grad, -1)
+	z := applyPreconditioner(residual, precond)
+	direction := z.Copy()
+	residualDot := gradientDot(residual, z)
+	for i := 0; i < iters; i++ {
+		product := h.curvatureProduct(s, direction)
+		product.Add(scaledGradient(direction, damping))
+		curvature := gradientDot(direction, product)
+		if curvature <= 0 {
+			if i == 0 {
+				if precond == nil {
+					z.Scale(1 / damping)
+				}
+				return z
+			}
+			break
+		}
+		alpha := residualDot / curvature
+		solution.Add(scaledGradient(direction, alpha))
+		residual.Add(scaledGradient(product, -alpha))
+		if math.Sqrt(gradientDot(residual, residual)) <= tol {
+			break
+		}
+		z = applyPreconditioner(residual, precond)
+		newResidualDot := gradientDot(residual, z)
+		direction.Scale(newResidualDot / residualDot)
+		direction.Add(z)
+		residualDot = newResidualDot
+	}
+	return solution
+}
+
+// curvatureProduct computes the product of the curvature
+// matrix and a vector.
+func (h *HessianFree) curvatureProduct(s SampleSet, vec autofunc.Gradient) autofunc.Gradient {
+	_, rGrad := h.RGradienter.RGradient(autofunc.RVector(vec), s)
+	return autofunc.Gradient(rGrad).Copy()
+}
+
+// preconditioner estimates the magnitudes of the rows of
+// the curvature matrix like Equilibration, and returns
+// the diagonal of a preconditioner for the damped
+// curvature, or nil if preconditioning is disabled.
+func (h *HessianFree) preconditioner(s SampleSet, grad autofunc.Gradient,
+	damping float64) autofunc.Gradient {
+	if h.PreconditionSamples == 0 {
+		return nil
+	}
+	normFloat := rand.NormFloat64
+	if h.Rand != nil {
+		normFloat = rand.New(h.Rand).NormFloat64
+	}
+	squareMags := scaledGradient(grad, 0)
+	vec := scaledGradient(grad, 0)
+	for i := 0; i < h.PreconditionSamples; i++ {
+		for _, p := range h.Learner.Parameters() {
+			v := vec[p]
+			for j := range v {
+				v[j] = normFloat()
+			}
+		}
+		for variable, v := range h.curvatureProduct(s, vec) {
+			mags := squareMags[variable]
+			for j, x := range v {
+				mags[j] += x * x
+			}
+		}
+	}
+	for _, v := range squareMags {
+		for i, x := range v {
+			v[i] = math.Sqrt(x/float64(h.PreconditionSamples)) + damping
+		}
+	}
+	return squareMags
+}
+
+func applyPreconditioner(g, precond autofunc.Gradient) autofunc.Gradient {
+	res := g.Copy()
+	if precond == nil {
+		return res
+	}
+	for variable, vec := range res {
+		diag := precond[variable]
+		for i := range vec {
+			vec[i] /= diag[i]
+		}
+	}
+	return res
+}
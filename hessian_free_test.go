@@ -0,0 +1,98 @@
+package sgd
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+func (q *quadraticTestGradienter) RGradient(rv autofunc.RVector,
+	s SampleSet) (autofunc.Gradient, autofunc.RGradient) {
+	rGrad := autofunc.NewRGradient(q.Parameters())
+	for i, x := range rv[q.Var] {
+		rGrad[q.Var][i] = q.Scales[i] * x
+	}
+	return q.Gradient(s), rGrad
+}
+
+// rosenbrockTestGradienter computes the Rosenbrock
+// function of a two-dimensional variable.
+type rosenbrockTestGradienter struct {
+	Var *autofunc.Variable
+}
+
+func (r *rosenbrockTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	x, y := r.Var.Vector[0], r.Var.Vector[1]
+	return autofunc.Gradient{
+		r.Var: {-2*(1-x) - 400*x*(y-x*x), 200 * (y - x*x)},
+	}
+}
+
+func (r *rosenbrockTestGradienter) RGradient(rv autofunc.RVector,
+	s SampleSet) (autofunc.Gradient, autofunc.RGradient) {
+	x, y := r.Var.Vector[0], r.Var.Vector[1]
+	v := rv[r.Var]
+	hxx := 2 - 400*(y-x*x) + 800*x*x
+	hxy := -400 * x
+	return r.Gradient(s), autofunc.RGradient{
+		r.Var: {hxx*v[0] + hxy*v[1], hxy*v[0] + 200*v[1]},
+	}
+}
+
+func (r *rosenbrockTestGradienter) Cost(s SampleSet) float64 {
+	x, y := r.Var.Vector[0], r.Var.Vector[1]
+	return (1-x)*(1-x) + 100*(y-x*x)*(y-x*x)
+}
+
+func TestHessianFreeQuadratic(t *testing.T) {
+	for _, samples := range []int{0, 3} {
+		q := newQuadraticTestGradienter()
+		h := &HessianFree{
+			RGradienter:         q,
+			Coster:              q,
+			Damping:             1e-3,
+			PreconditionSamples: samples,
+			Learner:             q,
+			Rand:                NewRandSource(1),
+		}
+		res, err := h.Minimize(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Reason != GradientConverged || res.Iterations > 10 {
+			t.Errorf("samples=%d: unexpected result %+v", samples, res)
+		}
+	}
+}
+
+func TestHessianFreeRosenbrock(t *testing.T) {
+	r := &rosenbrockTestGradienter{
+		Var: &autofunc.Variable{Vector: []float64{-1.2, 1}},
+	}
+	h := &HessianFree{RGradienter: r, Coster: r, MaxIters: 200}
+	res, err := h.Minimize(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != GradientConverged {
+		t.Errorf("unexpected result %+v", res)
+	}
+	for i, x := range r.Var.Vector {
+		if math.Abs(x-1) > 1e-4 {
+			t.Errorf("component %d: expected 1 but got %f", i, x)
+		}
+	}
+}
+
+func TestHessianFreeNoLearner(t *testing.T) {
+	q := newQuadraticTestGradienter()
+	h := &HessianFree{RGradienter: q, Coster: q, PreconditionSamples: 3}
+	if _, err := h.Minimize(context.Background(), nil); err == nil {
+		t.Error("expected an error")
+	}
+	if q.Var.Vector[0] != 1 || q.Var.Vector[1] != -1 || q.Var.Vector[2] != 2 {
+		t.Errorf("parameters changed: %v", q.Var.Vector)
+	}
+}
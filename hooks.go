@@ -0,0 +1,110 @@
+package sgd
+
+import "github.com/unixpickle/autofunc"
+
+// StepInfo describes the state of a Trainer when one of
+// its hooks is called.
+type StepInfo struct {
+	// Batch is the current mini-batch.
+	// It is nil outside of BeforeBatch and AfterBatch.
+	Batch SampleSet
+
+	// Gradient is the gradient computed for Batch.
+	// It is only set during AfterBatch, and it is only
+	// valid until the next step.
+	// It is nil if the Gradienter did not produce an
+	// update for this batch (e.g. an Accumulator which
+	// has not yet filled its virtual batch).
+	Gradient autofunc.Gradient
+
+	// StepSize is the step size for the current batch.
+	// If the Trainer uses a line search, then this is the
+	// initial guess during BeforeBatch and the actual step
+	// during AfterBatch.
+	StepSize float64
+
+	// Step and Epoch are the number of steps and epochs
+	// which have been completed so far.
+	Step  int
+	Epoch int
+
+	// EpochStep is the number of batches which have been
+	// processed in the current epoch.
+	EpochStep int
+}
+
+// A Hook observes a Trainer as it runs.
+//
+// If any method besides OnStop returns a non-nil error,
+// the Trainer stops and returns said error.
+// Hooks may return ErrStopped to request a normal stop.
+//
+// The *StepInfo is reused by the Trainer, so it is only
+// valid during the call.
+// A hook which needs the information later must copy it,
+// and a hook must not modify it.
+type Hook interface {
+	// BeforeEpoch is called before each epoch, before the
+	// samples have been shuffled.
+	BeforeEpoch(info *StepInfo) error
+
+	// BeforeBatch is called before the gradient is
+	// computed for a mini-batch.
+	BeforeBatch(info *StepInfo) error
+
+	// AfterBatch is called after the parameters have been
+	// updated for a mini-batch.
+	AfterBatch(info *StepInfo) error
+
+	// AfterEpoch is called after each complete epoch.
+	AfterEpoch(info *StepInfo) error
+
+	// OnStop is called once when training stops, with the
+	// same error which the Trainer is about to return.
+	OnStop(info *StepInfo, reason error)
+}
+
+// HookFuncs is a Hook which calls a function for each of
+// the Hook methods.
+// Nil functions are ignored.
+type HookFuncs struct {
+	BeforeEpochFunc func(info *StepInfo) error
+	BeforeBatchFunc func(info *StepInfo) error
+	AfterBatchFunc  func(info *StepInfo) error
+	AfterEpochFunc  func(info *StepInfo) error
+	OnStopFunc      func(info *StepInfo, reason error)
+}
+
+func (h *HookFuncs) BeforeEpoch(info *StepInfo) error {
+	if h.BeforeEpochFunc == nil {
+		return nil
+	}
+	return h.BeforeEpochFunc(info)
+}
+
+func (h *HookFuncs) BeforeBatch(info *StepInfo) error {
+	if h.BeforeBatchFunc == nil {
+		return nil
+	}
+	return h.BeforeBatchFunc(info)
+}
+
+func (h *HookFuncs) AfterBatch(info *StepInfo) error {
+	if h.AfterBatchFunc == nil {
+		return nil
+	}
+	return h.AfterBatchFunc(info)
+}
+
+func (h *HookFuncs) AfterEpoch(info *StepInfo) error {
+	if h.AfterEpochFunc == nil {
+		return nil
+	}
+	return h.AfterEpochFunc(info)
+}
+
+func (h *HookFuncs) OnStop(info *StepInfo, reason error) {
+	if h.OnStopFunc != nil {
+		h.OnStopFunc(info, reason)
+	}
+}
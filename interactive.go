@@ -3,13 +3,13 @@
 package sgd
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"os/signal"
 	"sync/atomic"
 )
 
-func loopUntilKilled(sf func() bool, tf func()) {
+func loopUntilKilled(sf func() bool, tf func(), onInterrupt func()) {
 	var killed uint32
 
 	c := make(chan os.Signal, 1)
@@ -31,7 +31,7 @@ func loopUntilKilled(sf func() bool, tf func()) {
 		signal.Stop(c)
 		close(c)
 		atomic.StoreUint32(&killed, 1)
-		fmt.Println("\nCaught interrupt. Ctrl+C again to terminate.")
+		onInterrupt()
 	}()
 
 	for atomic.LoadUint32(&killed) == 0 {
@@ -46,3 +46,32 @@ func loopUntilKilled(sf func() bool, tf func()) {
 		tf()
 	}
 }
+
+// InterruptContext returns a context which is cancelled
+// when the process receives an os.Interrupt.
+//
+// If events is non-nil, an InterruptEvent is emitted to it
+// when the interrupt is caught.
+//
+// The signal handler is removed as soon as the context is
+// done, so a second interrupt behaves normally.
+// Callers should call the returned CancelFunc once they are
+// done with the context to release the handler.
+func InterruptContext(ctx context.Context, events EventSink) (context.Context,
+	context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		select {
+		case <-c:
+			if events != nil {
+				events.Emit(&InterruptEvent{})
+			}
+		case <-ctx.Done():
+		}
+		signal.Stop(c)
+		cancel()
+	}()
+	return ctx, cancel
+}
@@ -2,7 +2,9 @@
 
 package sgd
 
-func loopUntilKilled(sf func() bool, tf func()) {
+import "context"
+
+func loopUntilKilled(sf func() bool, tf func(), onInterrupt func()) {
 	for {
 		if !sf() {
 			return
@@ -10,3 +12,13 @@ func loopUntilKilled(sf func() bool, tf func()) {
 		tf()
 	}
 }
+
+// InterruptContext returns a cancellable child of ctx.
+//
+// Interrupts are not supported on this platform, so the
+// context is only cancelled through the CancelFunc or its
+// parent, and events is never used.
+func InterruptContext(ctx context.Context, events EventSink) (context.Context,
+	context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,59 @@
+// +build !js,!windows
+
+package sgd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInterruptContext(t *testing.T) {
+	// Keep the process alive if InterruptContext fails to
+	// catch the signal.
+	guard := make(chan os.Signal, 2)
+	signal.Notify(guard, os.Interrupt)
+	defer signal.Stop(guard)
+
+	sink := &MemorySink{}
+	ctx, cancel := InterruptContext(context.Background(), sink)
+	defer cancel()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("context was not cancelled by interrupt")
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("unexpected error: %v", ctx.Err())
+	}
+	events := sink.Events()
+	if len(events) != 1 || events[0].EventType() != "interrupt" {
+		t.Errorf("unexpected events: %v", events)
+	}
+}
+
+func TestInterruptContextRelease(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		_, cancel := InterruptContext(context.Background(), nil)
+		cancel()
+	}
+
+	// The handler goroutines exit after calling
+	// signal.Stop, so none should be left behind.
+	deadline := time.Now().Add(10 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d goroutines but have %d", before,
+				runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
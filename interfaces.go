@@ -18,6 +18,12 @@ type Gradienter interface {
 	// The returned result is only valid until the
 	// next call to Gradient (or to RGradient, if
 	// this is also an RGradienter)
+	//
+	// The result may be nil, meaning that no update
+	// should be made for these samples (e.g. because
+	// an Accumulator is still filling a virtual batch).
+	// Training loops skip the update in this case, and
+	// Transformers pass the nil gradient along.
 	Gradient(SampleSet) autofunc.Gradient
 }
 
@@ -42,6 +48,18 @@ type RGradienter interface {
 // The Transform method may modify its argument, and its
 // return value is not guaranteed to be related to its
 // argument in any way.
+//
+// Transform must return nil when it is passed nil,
+// without updating any internal state.
 type Transformer interface {
 	Transform(autofunc.Gradient) autofunc.Gradient
 }
+
+// A Coster is anything which can compute the total cost
+// (i.e. the error being minimized) for a set of samples.
+//
+// Just like for Gradienter, it is not safe to call a
+// Coster's methods concurrently.
+type Coster interface {
+	Cost(SampleSet) float64
+}
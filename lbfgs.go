@@ -0,0 +1,241 @@
+package sgd
+
+import (
+	"context"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+)
+
+const (
+	lbfgsDefaultHistory       = 10
+	lbfgsDefaultMaxIters      = 100
+	lbfgsDefaultGradTolerance = 1e-5
+)
+
+// A TerminationReason indicates why a full-batch
+// optimizer stopped.
+type TerminationReason int
+
+const (
+	// GradientConverged means that the gradient became
+	// smaller than the gradient tolerance.
+	GradientConverged TerminationReason = iota
+
+	// CostConverged means that the cost stopped
+	// decreasing by more than the cost tolerance.
+	CostConverged
+
+	// IterationLimit means that the maximum number of
+	// iterations was reached.
+	IterationLimit
+
+	// LineSearchFailed means that no step which
+	// decreased the cost could be found.
+	LineSearchFailed
+
+	// Canceled means that the context was done.
+	Canceled
+)
+
+// String returns a human-readable description of the
+// reason.
+func (t TerminationReason) String() string {
+	switch t {
+	case GradientConverged:
+		return "gradient converged"
+	case CostConverged:
+		return "cost converged"
+	case IterationLimit:
+		return "iteration limit"
+	case LineSearchFailed:
+		return "line search failed"
+	case Canceled:
+		return "canceled"
+	default:
+		return "unknown reason"
+	}
+}
+
+// An OptimizerResult describes the outcome of a run of a
+// full-batch optimizer.
+type OptimizerResult struct {
+	// Iterations is the number of steps which were taken.
+	Iterations int
+
+	// Cost is the final cost.
+	Cost float64
+
+	Reason TerminationReason
+}
+
+// LBFGS implements the limited-memory BFGS quasi-Newton
+// method, for problems which are small enough to compute
+// the gradient and cost over the entire data set.
+//
+// Unlike the SGD variants in this package, LBFGS is not a
+// Transformer, since it decides how far to step on its
+// own.
+// Its Gradienter must never return nil.
+type LBFGS struct {
+	Gradienter Gradienter
+	Coster     Coster
+
+	// LineSearch is used to choose the step along each
+	// search direction.
+	// If it is nil, a Wolfe line search using Gradienter
+	// and Coster is used.
+	// Since the BFGS update relies on the curvature
+	// condition, the line search should satisfy the Wolfe
+	// conditions.
+	LineSearch LineSearcher
+
+	// History is the number of recent steps used to
+	// approximate the inverse Hessian.
+	// If it is 0, a default of 10 is used.
+	History int
+
+	// MaxIters is the maximum number of steps.
+	// If it is 0, a default of 100 is used.
+	MaxIters int
+
+	// GradTolerance is the largest absolute gradient
+	// component at which the optimization is considered
+	// converged.
+	// If it is 0, a default of 1e-5 is used.
+	GradTolerance float64
+
+	// CostTolerance, if non-zero, stops the optimization
+	// when a step decreases the cost by less than this
+	// fraction of the cost's magnitude.
+	CostTolerance float64
+}
+
+// Minimize runs LBFGS on the samples until one of the
+// stopping conditions is met, or until ctx is done.
+//
+// If the context is done, the result is returned along
+// with the context's error.
+func (l *LBFGS) Minimize(ctx context.Context, s SampleSet) (*OptimizerResult, error) {
+	history := defaultInt(l.History, lbfgsDefaultHistory)
+	maxIters := defaultInt(l.MaxIters, lbfgsDefaultMaxIters)
+	gradTol := defaultFloat(l.GradTolerance, lbfgsDefaultGradTolerance)
+	search := l.lineSearcher()
+
+	cost := l.Coster.Cost(s)
+	grad := mustGradient(l.Gradienter, s).Copy()
+	res := &OptimizerResult{Cost: cost}
+	var steps, diffs []autofunc.Gradient
+
+	for {
+		if gradientMaxAbs(grad) <= gradTol {
+			res.Reason = GradientConverged
+			return res, nil
+		}
+		if res.Iterations >= maxIters {
+			res.Reason = IterationLimit
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			res.Reason = Canceled
+			return res, ctx.Err()
+		default:
+		}
+
+		dir := lbfgsDirection(grad, steps, diffs)
+		initStep := 1.0
+		if len(steps) == 0 {
+			initStep = 1 / math.Sqrt(gradientDot(grad, grad))
+		}
+		step, newCost := search.lineSearchCost(s, cost, grad, dir, initStep)
+		if step == 0 {
+			if len(steps) == 0 {
+				res.Reason = LineSearchFailed
+				return res, nil
+			}
+			// The approximate inverse Hessian may be bad, so
+			// start over with steepest descent.
+			steps, diffs = nil, nil
+			continue
+		}
+
+		newGrad := mustGradient(l.Gradienter, s).Copy()
+		dir.Scale(step)
+		diff := newGrad.Copy()
+		diff.Add(scaledGradient(grad, -1))
+		if gradientDot(dir, diff) > 0 {
+			steps = append(steps, dir)
+			diffs = append(diffs, diff)
+			if len(steps) > history {
+				steps, diffs = steps[1:], diffs[1:]
+			}
+		}
+
+		res.Iterations++
+		res.Cost = newCost
+		decrease := cost - newCost
+		cost, grad = newCost, newGrad
+		if l.CostTolerance != 0 && decrease <= l.CostTolerance*math.Abs(cost) {
+			res.Reason = CostConverged
+			return res, nil
+		}
+	}
+}
+
+func (l *LBFGS) lineSearcher() costLineSearcher {
+	if l.LineSearch == nil {
+		return &Wolfe{Coster: l.Coster, Gradienter: l.Gradienter}
+	}
+	if c, ok := l.LineSearch.(costLineSearcher); ok {
+		return c
+	}
+	return genericLineSearcher{l.LineSearch}
+}
+
+// lbfgsDirection computes the search direction using the
+// two-loop recursion from Nocedal and Wright's "Numerical
+// Optimization" (algorithm 7.4).
+func lbfgsDirection(grad autofunc.Gradient, steps, diffs []autofunc.Gradient) autofunc.Gradient {
+	dir := scaledGradient(grad, -1)
+	alphas := make([]float64, len(steps))
+	for i := len(steps) - 1; i >= 0; i-- {
+		rho := 1 / gradientDot(steps[i], diffs[i])
+		alphas[i] = rho * gradientDot(steps[i], dir)
+		dir.Add(scaledGradient(diffs[i], -alphas[i]))
+	}
+	if n := len(steps); n > 0 {
+		dir.Scale(gradientDot(steps[n-1], diffs[n-1]) / gradientDot(diffs[n-1], diffs[n-1]))
+	}
+	for i, step := range steps {
+		rho := 1 / gradientDot(step, diffs[i])
+		beta := rho * gradientDot(diffs[i], dir)
+		dir.Add(scaledGradient(step, alphas[i]-beta))
+	}
+	return dir
+}
+
+func scaledGradient(g autofunc.Gradient, scale float64) autofunc.Gradient {
+	res := g.Copy()
+	res.Scale(scale)
+	return res
+}
+
+// mustGradient computes a gradient which is needed for
+// every call, panicking if g returns nil (e.g. because it
+// is an Accumulator).
+func mustGradient(g Gradienter, s SampleSet) autofunc.Gradient {
+	res := g.Gradient(s)
+	if res == nil {
+		panic("Gradienter returned nil where a gradient is required")
+	}
+	return res
+}
+
+func gradientMaxAbs(g autofunc.Gradient) float64 {
+	var res float64
+	for _, vec := range g {
+		res = math.Max(res, vec.MaxAbs())
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package sgd
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLBFGS(t *testing.T) {
+	for _, searcher := range []string{"wolfe", "backtracking"} {
+		q := newQuadraticTestGradienter()
+		l := &LBFGS{Gradienter: q, Coster: q, History: 5}
+		if searcher == "backtracking" {
+			l.LineSearch = &Backtracking{Coster: q}
+		}
+		res, err := l.Minimize(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Reason != GradientConverged {
+			t.Errorf("%s: unexpected reason: %s", searcher, res.Reason)
+		}
+		if res.Iterations > 30 {
+			t.Errorf("%s: took %d iterations", searcher, res.Iterations)
+		}
+		if cost := q.Cost(nil); cost != res.Cost || cost > 1e-9 {
+			t.Errorf("%s: unexpected cost %e (result says %e)", searcher, cost, res.Cost)
+		}
+	}
+}
+
+func TestLBFGSLimits(t *testing.T) {
+	q := newQuadraticTestGradienter()
+	l := &LBFGS{Gradienter: q, Coster: q, MaxIters: 2}
+	res, err := l.Minimize(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != IterationLimit || res.Iterations != 2 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = l.Minimize(ctx, nil)
+	if err != context.Canceled || res.Reason != Canceled {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,322 @@
+package sgd
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+)
+
+const (
+	defaultLineSearchC1       = 1e-4
+	defaultWolfeC2            = 0.9
+	defaultBacktrackingShrink = 0.5
+	defaultWolfeGrowth        = 2
+	defaultLineSearchIters    = 20
+)
+
+// A LineSearcher chooses how far to move the parameters
+// along a search direction, and then moves them.
+type LineSearcher interface {
+	// LineSearch moves the parameters along dir, which
+	// should be a descent direction, and returns the
+	// step length that was used along with the cost at
+	// the new parameters.
+	// The step length is the multiple of dir that was
+	// added to the parameters.
+	//
+	// The grad argument is the gradient of the cost at
+	// the current parameters.
+	// It is only read before any new gradients are
+	// computed, so it may be the (soon to be invalid)
+	// result of a Gradienter.
+	//
+	// The step argument is the first step length to
+	// try.
+	//
+	// If no acceptable step is found, the parameters are
+	// left where they were and a step of 0 is returned.
+	LineSearch(s SampleSet, grad, dir autofunc.Gradient, step float64) (float64, float64)
+}
+
+// costLineSearcher is a LineSearcher which can avoid
+// recomputing the cost at the current parameters.
+type costLineSearcher interface {
+	lineSearchCost(s SampleSet, cost float64, grad, dir autofunc.Gradient,
+		step float64) (float64, float64)
+}
+
+type genericLineSearcher struct {
+	LineSearcher
+}
+
+func (g genericLineSearcher) lineSearchCost(s SampleSet, cost float64,
+	grad, dir autofunc.Gradient, step float64) (float64, float64) {
+	step, newCost := g.LineSearch(s, grad, dir, step)
+	if step == 0 {
+		return 0, cost
+	}
+	return step, newCost
+}
+
+// Backtracking is a LineSearcher which shrinks the step
+// until it satisfies the Armijo (sufficient decrease)
+// condition.
+type Backtracking struct {
+	Coster Coster
+
+	// C1 is the fraction of the decrease predicted by the
+	// gradient which a step must achieve.
+	// If it is 0, a default of 1e-4 is used.
+	C1 float64
+
+	// Shrink is multiplied into the step after each
+	// unacceptable guess.
+	// If it is 0, a default of 0.5 is used.
+	Shrink float64
+
+	// MaxIters is the maximum number of steps to try.
+	// If it is 0, a default of 20 is used.
+	MaxIters int
+}
+
+func (b *Backtracking) LineSearch(s SampleSet, grad, dir autofunc.Gradient,
+	step float64) (float64, float64) {
+	return b.lineSearchCost(s, b.Coster.Cost(s), grad, dir, step)
+}
+
+func (b *Backtracking) lineSearchCost(s SampleSet, cost float64, grad, dir autofunc.Gradient,
+	step float64) (float64, float64) {
+	c1 := defaultFloat(b.C1, defaultLineSearchC1)
+	shrink := defaultFloat(b.Shrink, defaultBacktrackingShrink)
+	iters := defaultInt(b.MaxIters, defaultLineSearchIters)
+
+	slope := gradientDot(grad, dir)
+	if slope >= 0 {
+		return 0, cost
+	}
+	line := &lineParams{dir: dir}
+	for i := 0; i < iters; i++ {
+		line.moveTo(step)
+		newCost := b.Coster.Cost(s)
+		if newCost <= cost+c1*step*slope {
+			return step, newCost
+		}
+		step *= shrink
+	}
+	line.moveTo(0)
+	return 0, cost
+}
+
+// Wolfe is a LineSearcher which finds a step satisfying
+// the strong Wolfe conditions, using the algorithm from
+// Nocedal and Wright's "Numerical Optimization" (chapter
+// 3.5).
+//
+// Unlike Backtracking, Wolfe may increase the step, and
+// it computes gradients at the points it tries, making it
+// suitable for quasi-Newton methods like LBFGS.
+type Wolfe struct {
+	Coster Coster
+
+	// Gradienter computes the gradients at the points
+	// which are tried.
+	// It should compute the raw gradient of the cost,
+	// without a Transformer such as Adam, Momentum, or
+	// Accumulator, since every point would advance the
+	// Transformer's state.
+	//
+	// If Gradienter returns nil, the search fails, and the
+	// parameters are left where they were.
+	Gradienter Gradienter
+
+	// C1 is the sufficient decrease constant, and C2 is
+	// the curvature constant.
+	// If they are 0, defaults of 1e-4 and 0.9 are used.
+	C1 float64
+	C2 float64
+
+	// Growth is multiplied into the step while the step
+	// is still too small.
+	// If it is 0, a default of 2 is used.
+	Growth float64
+
+	// MaxStep is the largest step to try.
+	// If it is 0, there is no maximum.
+	MaxStep float64
+
+	// MaxIters is the maximum number of points to try,
+	// both while growing the step and while narrowing it
+	// down.
+	// If it is 0, a default of 20 is used.
+	MaxIters int
+}
+
+func (w *Wolfe) LineSearch(s SampleSet, grad, dir autofunc.Gradient,
+	step float64) (float64, float64) {
+	return w.lineSearchCost(s, w.Coster.Cost(s), grad, dir, step)
+}
+
+func (w *Wolfe) lineSearchCost(s SampleSet, cost float64, grad, dir autofunc.Gradient,
+	step float64) (float64, float64) {
+	c1 := defaultFloat(w.C1, defaultLineSearchC1)
+	c2 := defaultFloat(w.C2, defaultWolfeC2)
+	growth := defaultFloat(w.Growth, defaultWolfeGrowth)
+	iters := defaultInt(w.MaxIters, defaultLineSearchIters)
+
+	slope := gradientDot(grad, dir)
+	if slope >= 0 {
+		return 0, cost
+	}
+	search := &wolfeSearch{
+		Wolfe:   w,
+		samples: s,
+		line:    &lineParams{dir: dir},
+		cost:    cost,
+		slope:   slope,
+		c1:      c1,
+		c2:      c2,
+		iters:   iters,
+	}
+
+	prevStep, prevCost, prevSlope := 0.0, cost, slope
+	for i := 0; i < iters; i++ {
+		if w.MaxStep != 0 && step > w.MaxStep {
+			step = w.MaxStep
+		}
+		newCost := search.costAt(step)
+		if newCost > cost+c1*step*slope || (i > 0 && newCost >= prevCost) {
+			return search.zoom(prevStep, step, prevCost, newCost, prevSlope)
+		}
+		newSlope, ok := search.slopeAt()
+		if !ok {
+			search.line.moveTo(0)
+			return 0, cost
+		}
+		if math.Abs(newSlope) <= -c2*slope {
+			return step, newCost
+		}
+		if newSlope >= 0 {
+			return search.zoom(step, prevStep, newCost, prevCost, newSlope)
+		}
+		if w.MaxStep != 0 && step == w.MaxStep {
+			return step, newCost
+		}
+		prevStep, prevCost, prevSlope = step, newCost, newSlope
+		step *= growth
+	}
+	search.line.moveTo(prevStep)
+	return prevStep, prevCost
+}
+
+type wolfeSearch struct {
+	*Wolfe
+
+	samples SampleSet
+	line    *lineParams
+
+	cost  float64
+	slope float64
+
+	c1    float64
+	c2    float64
+	iters int
+}
+
+func (w *wolfeSearch) costAt(step float64) float64 {
+	w.line.moveTo(step)
+	return w.Coster.Cost(w.samples)
+}
+
+// slopeAt computes the directional derivative at the
+// point last passed to costAt.
+// It returns false if the Gradienter returned nil.
+func (w *wolfeSearch) slopeAt() (float64, bool) {
+	grad := w.Gradienter.Gradient(w.samples)
+	if grad == nil {
+		return 0, false
+	}
+	return gradientDot(grad, w.line.dir), true
+}
+
+// zoom narrows down an interval which is known to contain
+// a step satisfying the strong Wolfe conditions.
+//
+// The lo step always satisfies the sufficient decrease
+// condition and has the lowest cost seen so far.
+func (w *wolfeSearch) zoom(lo, hi, loCost, hiCost, loSlope float64) (float64, float64) {
+	for i := 0; i < w.iters; i++ {
+		step := interpolateStep(lo, hi, loCost, hiCost, loSlope)
+		cost := w.costAt(step)
+		if cost > w.cost+w.c1*step*w.slope || cost >= loCost {
+			hi, hiCost = step, cost
+			continue
+		}
+		slope, ok := w.slopeAt()
+		if !ok {
+			w.line.moveTo(0)
+			return 0, w.cost
+		}
+		if math.Abs(slope) <= -w.c2*w.slope {
+			return step, cost
+		}
+		if slope*(hi-lo) >= 0 {
+			hi, hiCost = lo, loCost
+		}
+		lo, loCost, loSlope = step, cost, slope
+	}
+	w.line.moveTo(lo)
+	return lo, loCost
+}
+
+// interpolateStep finds the minimum of the quadratic that
+// matches the cost and slope at lo and the cost at hi,
+// falling back on bisection if the minimum is too close
+// to the ends of the interval.
+func interpolateStep(lo, hi, loCost, hiCost, loSlope float64) float64 {
+	delta := hi - lo
+	curvature := (hiCost - loCost - loSlope*delta) / (delta * delta)
+	if curvature > 0 {
+		step := lo - loSlope/(2*curvature)
+		margin := 0.1 * math.Abs(delta)
+		if step > math.Min(lo, hi)+margin && step < math.Max(lo, hi)-margin {
+			return step
+		}
+	}
+	return lo + delta/2
+}
+
+// lineParams moves parameters along a direction, keeping
+// track of how far they have been moved.
+type lineParams struct {
+	dir     autofunc.Gradient
+	current float64
+}
+
+func (l *lineParams) moveTo(step float64) {
+	l.dir.AddToVars(step - l.current)
+	l.current = step
+}
+
+func gradientDot(g1, g2 autofunc.Gradient) float64 {
+	var res float64
+	for variable, vec := range g1 {
+		if vec2, ok := g2[variable]; ok {
+			res += vec.Dot(vec2)
+		}
+	}
+	return res
+}
+
+func defaultFloat(x, def float64) float64 {
+	if x == 0 {
+		return def
+	}
+	return x
+}
+
+func defaultInt(x, def int) int {
+	if x == 0 {
+		return def
+	}
+	return x
+}
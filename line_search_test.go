@@ -0,0 +1,74 @@
+package sgd
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestWolfeConditions(t *testing.T) {
+	g := newTargetTestGradienter()
+	samples := SliceSampleSet{1.0, 2.0, -0.5}
+	wolfe := &Wolfe{Coster: g, Gradienter: g}
+
+	cost := g.Cost(samples)
+	grad := g.Gradient(samples)
+	dir := grad.Copy()
+	dir.Scale(-1)
+	slope := gradientDot(grad, dir)
+
+	// Start with a tiny step to force the step to grow.
+	step, newCost := wolfe.LineSearch(samples, grad, dir, 1e-4)
+	if step <= 0 {
+		t.Fatalf("invalid step: %f", step)
+	}
+	if math.Abs(newCost-g.Cost(samples)) > 1e-8 {
+		t.Errorf("reported cost %f does not match actual cost %f", newCost, g.Cost(samples))
+	}
+	if newCost > cost+defaultLineSearchC1*step*slope {
+		t.Errorf("sufficient decrease violated: cost %f -> %f", cost, newCost)
+	}
+	newSlope := gradientDot(g.Gradient(samples), dir)
+	if math.Abs(newSlope) > -defaultWolfeC2*slope {
+		t.Errorf("curvature condition violated: slope %f -> %f", slope, newSlope)
+	}
+}
+
+func TestWolfeNilGradient(t *testing.T) {
+	g := newTargetTestGradienter()
+	samples := SliceSampleSet{1.0, 2.0, -0.5}
+	wolfe := &Wolfe{Coster: g, Gradienter: &Accumulator{Gradienter: g, Steps: 2}}
+
+	cost := g.Cost(samples)
+	grad := g.Gradient(samples)
+	dir := grad.Copy()
+	dir.Scale(-1)
+	step, newCost := wolfe.LineSearch(samples, grad, dir, 1e-4)
+	if step != 0 || newCost != cost {
+		t.Errorf("expected failure but got step %f with cost %f", step, newCost)
+	}
+	if actual := g.Cost(samples); actual != cost {
+		t.Errorf("parameters moved: cost went from %f to %f", cost, actual)
+	}
+}
+
+func TestTrainerBacktracking(t *testing.T) {
+	g := newTargetTestGradienter()
+	samples := SliceSampleSet{1.0, 2.0, 3.0}
+	trainer := &Trainer{
+		Gradienter: g,
+		Samples:    samples,
+		BatchSize:  samples.Len(),
+		Schedule:   ConstantSchedule(1),
+		LineSearch: &Backtracking{Coster: g},
+		Budget:     Budget{Steps: 30},
+	}
+	trainer.Run(context.Background())
+	for _, v := range g.Vars {
+		for i, x := range v.Vector {
+			if math.Abs(x-2) > 1e-4 {
+				t.Errorf("entry %d: expected 2 but got %f", i, x)
+			}
+		}
+	}
+}
@@ -1,16 +1,46 @@
 package sgd
 
-import "github.com/unixpickle/autofunc"
+import (
+	"io"
 
-// Momentum implements basic momentum.
+	"github.com/unixpickle/autofunc"
+)
+
+// Momentum implements classical (heavy-ball) momentum and
+// Nesterov momentum.
 //
 // When used as a Gradienter, this will use its wrapped
 // Gradienter to acquire gradients and then pass said
 // gradients to Transform.
+//
+// The velocity only tracks the variables in the first
+// gradient.
+// Later gradients may lack some of those variables, and
+// variables which are not tracked are passed through
+// unchanged.
 type Momentum struct {
 	Gradienter Gradienter
 	Momentum   float64
 
+	// Dampening scales down the contribution of each new
+	// gradient to the velocity, which is updated as
+	//
+	//	v = Momentum*v + (1-Dampening)*g
+	//
+	// The first gradient is always used as the initial
+	// velocity without being dampened.
+	Dampening float64
+
+	// Nesterov, if true, enables Nesterov momentum.
+	//
+	// Rather than evaluating the gradient at a "lookahead"
+	// point, this uses the equivalent formulation in which
+	// the parameters are tracked at the lookahead point
+	// and the step is g + Momentum*v.
+	// Thus, it only needs the gradient at the current
+	// parameters and works like any other Transformer.
+	Nesterov bool
+
 	velocity autofunc.Gradient
 }
 
@@ -19,14 +49,54 @@ func (m *Momentum) Gradient(s SampleSet) autofunc.Gradient {
 }
 
 func (m *Momentum) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
 	if m.velocity == nil {
 		m.velocity = grad.Copy()
 	} else {
 		m.velocity.Scale(m.Momentum)
-		m.velocity.Add(grad)
+		for variable, velocity := range m.velocity {
+			if vec, ok := grad[variable]; ok {
+				for i, x := range vec {
+					velocity[i] += (1 - m.Dampening) * x
+				}
+			}
+		}
 	}
 	for variable, vec := range m.velocity {
-		copy(grad[variable], vec)
+		gradVec, ok := grad[variable]
+		if !ok {
+			continue
+		}
+		if m.Nesterov {
+			for i, x := range vec {
+				gradVec[i] += m.Momentum * x
+			}
+		} else {
+			copy(gradVec, vec)
+		}
 	}
 	return grad
 }
+
+// SaveState saves the velocity.
+func (m *Momentum) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	if err := writeStateHeader(w, "Momentum"); err != nil {
+		return err
+	}
+	return writeStateGradient(w, m.velocity, params)
+}
+
+// LoadState loads state saved with SaveState.
+func (m *Momentum) LoadState(r io.Reader, params []*autofunc.Variable) error {
+	if err := readStateHeader(r, "Momentum"); err != nil {
+		return err
+	}
+	velocity, err := readStateGradient(r, params)
+	if err != nil {
+		return err
+	}
+	m.velocity = velocity
+	return nil
+}
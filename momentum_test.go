@@ -0,0 +1,131 @@
+package sgd
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// quadraticTestGradienter computes the gradient of the
+// function 0.5*sum(Scales[i]*x[i]^2), ignoring its
+// samples.
+type quadraticTestGradienter struct {
+	Var    *autofunc.Variable
+	Scales []float64
+}
+
+func newQuadraticTestGradienter() *quadraticTestGradienter {
+	return &quadraticTestGradienter{
+		Var:    &autofunc.Variable{Vector: []float64{1, -1, 2}},
+		Scales: []float64{1, 10, 100},
+	}
+}
+
+func (q *quadraticTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	grad := autofunc.NewGradient(q.Parameters())
+	for i, x := range q.Var.Vector {
+		grad[q.Var][i] = q.Scales[i] * x
+	}
+	return grad
+}
+
+func (q *quadraticTestGradienter) Cost(s SampleSet) float64 {
+	var res float64
+	for i, x := range q.Var.Vector {
+		res += 0.5 * q.Scales[i] * x * x
+	}
+	return res
+}
+
+func (q *quadraticTestGradienter) Parameters() []*autofunc.Variable {
+	return []*autofunc.Variable{q.Var}
+}
+
+// runQuadraticTest takes steps with a Gradienter which
+// wraps q and returns the final cost.
+func runQuadraticTest(q *quadraticTestGradienter, g Gradienter, steps int,
+	stepSize float64) float64 {
+	for i := 0; i < steps; i++ {
+		grad := g.Gradient(nil)
+		if grad != nil {
+			grad.AddToVars(-stepSize)
+		}
+	}
+	return q.Cost(nil)
+}
+
+func TestMomentumNesterov(t *testing.T) {
+	var costs [2]float64
+	for i, nesterov := range []bool{false, true} {
+		q := newQuadraticTestGradienter()
+		m := &Momentum{Gradienter: q, Momentum: 0.9, Nesterov: nesterov}
+		costs[i] = runQuadraticTest(q, m, 100, 0.01)
+	}
+	if costs[0] < 1e-4 || costs[1] > costs[0]/10 {
+		t.Errorf("expected Nesterov to be much faster, but got costs %v", costs)
+	}
+}
+
+func TestMomentumDampening(t *testing.T) {
+	q := newQuadraticTestGradienter()
+	q.Var.Vector = []float64{1, 1, 1}
+	m := &Momentum{Gradienter: q, Momentum: 0.5, Dampening: 0.25}
+	var grad autofunc.Gradient
+	for i := 0; i < 100; i++ {
+		grad = m.Gradient(nil)
+	}
+	for i, x := range grad[q.Var] {
+		expected := q.Scales[i] * 0.75 / 0.5
+		if math.Abs(x-expected) > 1e-8 {
+			t.Errorf("entry %d: expected %f but got %f", i, expected, x)
+		}
+	}
+}
+
+func TestMomentumState(t *testing.T) {
+	q1 := newQuadraticTestGradienter()
+	m1 := &Momentum{Gradienter: q1, Momentum: 0.9, Nesterov: true}
+	runQuadraticTest(q1, m1, 3, 0.01)
+
+	var buf bytes.Buffer
+	if err := m1.SaveState(&buf, q1.Parameters()); err != nil {
+		t.Fatal(err)
+	}
+	q2 := newQuadraticTestGradienter()
+	copy(q2.Var.Vector, q1.Var.Vector)
+	m2 := &Momentum{Gradienter: q2, Momentum: 0.9, Nesterov: true}
+	if err := m2.LoadState(&buf, q2.Parameters()); err != nil {
+		t.Fatal(err)
+	}
+
+	runQuadraticTest(q1, m1, 5, 0.01)
+	runQuadraticTest(q2, m2, 5, 0.01)
+	for i, x := range q1.Var.Vector {
+		if q2.Var.Vector[i] != x {
+			t.Errorf("entry %d: expected %f but got %f", i, x, q2.Var.Vector[i])
+		}
+	}
+}
+
+func TestMomentumChangingVariables(t *testing.T) {
+	v1 := &autofunc.Variable{Vector: []float64{1, 2}}
+	v2 := &autofunc.Variable{Vector: []float64{3}}
+	for _, nesterov := range []bool{false, true} {
+		m := &Momentum{Momentum: 0.5, Nesterov: nesterov}
+		m.Transform(autofunc.Gradient{v1: []float64{1, 1}})
+		res := m.Transform(autofunc.Gradient{v2: []float64{2}})
+		if res[v2][0] != 2 {
+			t.Errorf("nesterov=%v: untracked variable changed to %f", nesterov, res[v2][0])
+		}
+		res = m.Transform(autofunc.Gradient{v1: []float64{1, 1}, v2: []float64{2}})
+		expected := 0.25 + 1
+		if nesterov {
+			expected = 1 + 0.5*expected
+		}
+		if math.Abs(res[v1][0]-expected) > 1e-8 || math.Abs(res[v1][1]-expected) > 1e-8 {
+			t.Errorf("nesterov=%v: expected %f but got %v", nesterov, expected, res[v1])
+		}
+	}
+}
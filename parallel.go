@@ -0,0 +1,138 @@
+package sgd
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// ParallelGradienter is a Gradienter which splits each
+// batch into pieces and computes the gradients of the
+// pieces on separate Goroutines.
+//
+// The workers' gradients are summed, so the result is the
+// same as it would be for a single Gradienter, up to
+// rounding error.
+//
+// The SampleSet passed to Gradient or RGradient must be
+// safe to read from multiple Goroutines at once.
+//
+// The workers' results are summed into the gradient (and
+// r-gradient) returned by the first worker, which is then
+// returned.
+// Thus, the first worker's result is modified in place,
+// the same way a Transformer modifies its input.
+// Workers which return nil gradients are skipped, and the
+// result is nil if every worker returns nil.
+type ParallelGradienter struct {
+	// New creates the Gradienter for a worker.
+	// It is called once for each worker, the first time
+	// the worker is needed.
+	//
+	// The Gradienters must all compute gradients for the
+	// same parameters, but they must not share any
+	// internal state which would make it unsafe to use
+	// them concurrently.
+	//
+	// If the Gradienters are RGradienters, then the
+	// ParallelGradienter may be used as an RGradienter.
+	New func() Gradienter
+
+	// Workers is the maximum number of Goroutines to use.
+	// If it is 0, runtime.GOMAXPROCS(0) is used.
+	Workers int
+
+	workers []Gradienter
+}
+
+// Gradient computes the gradient for s in parallel.
+//
+// The result is only valid until the next call to
+// Gradient or RGradient.
+func (p *ParallelGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	pieces := p.split(s)
+	grads := make([]autofunc.Gradient, len(pieces))
+	p.run(pieces, func(i int, w Gradienter, piece SampleSet) {
+		grads[i] = w.Gradient(piece)
+	})
+	var res autofunc.Gradient
+	for _, g := range grads {
+		if res == nil {
+			res = g
+		} else if g != nil {
+			res.Add(g)
+		}
+	}
+	return res
+}
+
+// RGradient computes the gradient and r-gradient for s in
+// parallel.
+// It panics if the workers are not RGradienters.
+//
+// The result is only valid until the next call to
+// Gradient or RGradient.
+func (p *ParallelGradienter) RGradient(v autofunc.RVector,
+	s SampleSet) (autofunc.Gradient, autofunc.RGradient) {
+	pieces := p.split(s)
+	grads := make([]autofunc.Gradient, len(pieces))
+	rGrads := make([]autofunc.RGradient, len(pieces))
+	p.run(pieces, func(i int, w Gradienter, piece SampleSet) {
+		grads[i], rGrads[i] = w.(RGradienter).RGradient(v, piece)
+	})
+	var res autofunc.Gradient
+	var rRes autofunc.RGradient
+	for i, g := range grads {
+		if res == nil {
+			res, rRes = g, rGrads[i]
+		} else if g != nil {
+			res.Add(g)
+			rRes.Add(rGrads[i])
+		}
+	}
+	return res, rRes
+}
+
+// split divides s into one contiguous piece per worker,
+// creating workers as needed.
+func (p *ParallelGradienter) split(s SampleSet) []SampleSet {
+	numWorkers := p.Workers
+	if numWorkers == 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	if numWorkers > s.Len() {
+		numWorkers = s.Len()
+	}
+	if numWorkers == 0 {
+		numWorkers = 1
+	}
+	for len(p.workers) < numWorkers {
+		p.workers = append(p.workers, p.New())
+	}
+
+	res := make([]SampleSet, numWorkers)
+	var start int
+	for i := range res {
+		end := start + (s.Len()-start)/(numWorkers-i)
+		res[i] = s.Subset(start, end)
+		start = end
+	}
+	return res
+}
+
+func (p *ParallelGradienter) run(pieces []SampleSet, f func(i int, w Gradienter, s SampleSet)) {
+	if len(pieces) == 1 {
+		f(0, p.workers[0], pieces[0])
+		return
+	}
+	var wg sync.WaitGroup
+	for i, piece := range pieces {
+		wg.Add(1)
+		go func(i int, piece SampleSet) {
+			defer wg.Done()
+			f(i, p.workers[i], piece)
+		}(i, piece)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,99 @@
+package sgd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// weightedTestGradienter computes a gradient and an
+// r-gradient that depend on each sample, which is a
+// float64 weight.
+type weightedTestGradienter struct {
+	Var *autofunc.Variable
+}
+
+func (w *weightedTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	grad, _ := w.RGradient(autofunc.RVector{}, s)
+	return grad
+}
+
+func (w *weightedTestGradienter) RGradient(rv autofunc.RVector,
+	s SampleSet) (autofunc.Gradient, autofunc.RGradient) {
+	grad := autofunc.NewGradient([]*autofunc.Variable{w.Var})
+	rGrad := autofunc.NewRGradient([]*autofunc.Variable{w.Var})
+	for i := 0; i < s.Len(); i++ {
+		weight := s.GetSample(i).(float64)
+		for j, x := range w.Var.Vector {
+			grad[w.Var][j] += weight * x * float64(j+1)
+			if rVec, ok := rv[w.Var]; ok {
+				rGrad[w.Var][j] += weight * x * rVec[j]
+			}
+		}
+	}
+	return grad, rGrad
+}
+
+func TestParallelGradienter(t *testing.T) {
+	v := &autofunc.Variable{Vector: []float64{1, -2, 3}}
+	serial := &weightedTestGradienter{Var: v}
+	rv := autofunc.RVector{v: []float64{0.5, 2, -1}}
+	samples := SliceSampleSet{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0}
+	for _, workers := range []int{1, 3, 7, 20} {
+		for _, batch := range []SampleSet{samples, samples.Subset(0, 2), samples.Subset(0, 0)} {
+			p := &ParallelGradienter{
+				New: func() Gradienter {
+					return &weightedTestGradienter{Var: v}
+				},
+				Workers: workers,
+			}
+			expected, expectedR := serial.RGradient(rv, batch)
+			actual, actualR := p.RGradient(rv, batch)
+			checkTestGradient(t, workers, batch.Len(), expected, actual)
+			checkTestGradient(t, workers, batch.Len(), autofunc.Gradient(expectedR),
+				autofunc.Gradient(actualR))
+			checkTestGradient(t, workers, batch.Len(), serial.Gradient(batch),
+				p.Gradient(batch))
+			if len(p.workers) > workers || len(p.workers) > batch.Len()+1 {
+				t.Errorf("workers %d, batch %d: created %d workers", workers, batch.Len(),
+					len(p.workers))
+			}
+		}
+	}
+}
+
+func checkTestGradient(t *testing.T, workers, size int, expected, actual autofunc.Gradient) {
+	if len(actual) != len(expected) {
+		t.Errorf("workers %d, batch %d: expected %d variables but got %d", workers, size,
+			len(expected), len(actual))
+		return
+	}
+	for variable, vec := range expected {
+		for i, x := range vec {
+			if a := actual[variable][i]; math.Abs(a-x) > 1e-8 {
+				t.Errorf("workers %d, batch %d: expected %v but got %v", workers, size,
+					vec, actual[variable])
+				break
+			}
+		}
+	}
+}
+
+func TestParallelGradienterNil(t *testing.T) {
+	v := &autofunc.Variable{Vector: []float64{1, -2, 3}}
+	p := &ParallelGradienter{
+		New: func() Gradienter {
+			return &Accumulator{Gradienter: &weightedTestGradienter{Var: v}, Steps: 2}
+		},
+		Workers: 2,
+	}
+	samples := SliceSampleSet{1.0, 2.0, 3.0, 4.0}
+	if grad := p.Gradient(samples); grad != nil {
+		t.Fatalf("expected nil gradient but got %v", grad)
+	}
+	// Each worker averages two gradients for the same
+	// piece, so the sum is the gradient of the batch.
+	expected := (&weightedTestGradienter{Var: v}).Gradient(samples)
+	checkTestGradient(t, 2, samples.Len(), expected, p.Gradient(samples))
+}
@@ -0,0 +1,185 @@
+package sgd
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrPrefetcherClosed is returned by a Prefetcher's
+// NextBatch method after the Prefetcher has been closed.
+var ErrPrefetcherClosed = errors.New("prefetcher closed")
+
+// A Prefetcher is a BatchSource which reads batches from
+// another BatchSource ahead of time on background
+// Goroutines, optionally preprocessing every sample.
+//
+// Batches are produced in the same order as the wrapped
+// BatchSource produces them, including the io.EOF at the
+// end of each epoch.
+// If the wrapped BatchSource or the preprocessing function
+// fails, the error is returned by NextBatch once all the
+// batches before it have been consumed, and is returned by
+// every call to NextBatch after that.
+type Prefetcher struct {
+	source     BatchSource
+	preprocess func(sample interface{}) (interface{}, error)
+
+	queue  chan *prefetchItem
+	jobs   chan *prefetchItem
+	closed chan struct{}
+	wg     sync.WaitGroup
+
+	closeOnce sync.Once
+	err       error
+}
+
+type prefetchItem struct {
+	batch SampleSet
+	err   error
+	done  chan struct{}
+}
+
+// NewPrefetcher creates a Prefetcher and starts reading
+// batches from src in the background.
+//
+// At most size+1 batches are read from src ahead of the
+// consumer: size batches which are queued (and may still
+// be preprocessing), plus one which is waiting for room
+// in the queue.
+// If preprocess is non-nil, it is applied to every sample
+// by a pool of workers Goroutines, and the batches
+// produced by the Prefetcher contain the results.
+// The preprocess function must be safe to call
+// concurrently.
+//
+// The Prefetcher takes ownership of src: src must not be
+// used by anything else until Close returns.
+func NewPrefetcher(src BatchSource, size, workers int,
+	preprocess func(sample interface{}) (interface{}, error)) *Prefetcher {
+	if size < 1 {
+		size = 1
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Prefetcher{
+		source:     src,
+		preprocess: preprocess,
+		queue:      make(chan *prefetchItem, size),
+		jobs:       make(chan *prefetchItem, size),
+		closed:     make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.readLoop()
+	if preprocess != nil {
+		for i := 0; i < workers; i++ {
+			p.wg.Add(1)
+			go p.workerLoop()
+		}
+	}
+	return p
+}
+
+// NextBatch returns the next prefetched batch, waiting for
+// it if necessary.
+//
+// Once Close has been called, NextBatch always returns
+// ErrPrefetcherClosed, even if batches were prefetched.
+func (p *Prefetcher) NextBatch() (SampleSet, error) {
+	if p.isClosed() {
+		return nil, ErrPrefetcherClosed
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	var item *prefetchItem
+	select {
+	case item = <-p.queue:
+	case <-p.closed:
+		return nil, ErrPrefetcherClosed
+	}
+	select {
+	case <-item.done:
+	case <-p.closed:
+		return nil, ErrPrefetcherClosed
+	}
+
+	// Both channels may have been ready, in which case the
+	// select statements could have picked either one.
+	if p.isClosed() {
+		return nil, ErrPrefetcherClosed
+	}
+
+	if item.err != nil && item.err != io.EOF {
+		p.err = item.err
+	}
+	return item.batch, item.err
+}
+
+// Close stops the background Goroutines and waits for
+// them to exit.
+//
+// If the wrapped BatchSource is in the middle of a call to
+// NextBatch, Close waits for that call to return.
+func (p *Prefetcher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		p.wg.Wait()
+	})
+}
+
+func (p *Prefetcher) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Prefetcher) readLoop() {
+	defer p.wg.Done()
+	defer close(p.jobs)
+	for {
+		batch, err := p.source.NextBatch()
+		item := &prefetchItem{batch: batch, err: err, done: make(chan struct{})}
+		if err != nil || p.preprocess == nil {
+			close(item.done)
+		} else {
+			select {
+			case p.jobs <- item:
+			case <-p.closed:
+				return
+			}
+		}
+		select {
+		case p.queue <- item:
+		case <-p.closed:
+			return
+		}
+		if err != nil && err != io.EOF {
+			return
+		}
+	}
+}
+
+func (p *Prefetcher) workerLoop() {
+	defer p.wg.Done()
+	for item := range p.jobs {
+		processed := make(SliceSampleSet, item.batch.Len())
+		for i := range processed {
+			sample, err := p.preprocess(item.batch.GetSample(i))
+			if err != nil {
+				item.batch = nil
+				item.err = err
+				break
+			}
+			processed[i] = sample
+		}
+		if item.err == nil {
+			item.batch = processed
+		}
+		close(item.done)
+	}
+}
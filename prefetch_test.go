@@ -0,0 +1,194 @@
+package sgd
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// scriptTestSource is a BatchSource which produces a fixed
+// sequence of results, then blocks on Block (if non-nil)
+// and returns io.EOF forever.
+//
+// If Calls is non-nil, every call to NextBatch sends to it
+// before doing anything else.
+type scriptTestSource struct {
+	Batches []SampleSet
+	Errs    []error
+	Block   chan struct{}
+	Calls   chan struct{}
+}
+
+func (s *scriptTestSource) NextBatch() (SampleSet, error) {
+	if s.Calls != nil {
+		s.Calls <- struct{}{}
+	}
+	if len(s.Batches) == 0 {
+		if s.Block != nil {
+			<-s.Block
+		}
+		return nil, io.EOF
+	}
+	batch, err := s.Batches[0], s.Errs[0]
+	s.Batches, s.Errs = s.Batches[1:], s.Errs[1:]
+	return batch, err
+}
+
+func (s *scriptTestSource) Add(batch SampleSet, err error) {
+	s.Batches = append(s.Batches, batch)
+	s.Errs = append(s.Errs, err)
+}
+
+func TestPrefetcherOrder(t *testing.T) {
+	src := &scriptTestSource{}
+	var expected []interface{}
+	for epoch := 0; epoch < 2; epoch++ {
+		for i := 0; i < 10; i++ {
+			batch := SliceSampleSet{}
+			for j := 0; j < 3; j++ {
+				batch = append(batch, epoch*100+i*3+j)
+			}
+			src.Add(batch, nil)
+			expected = append(expected, batch...)
+		}
+		src.Add(nil, io.EOF)
+		expected = append(expected, io.EOF)
+	}
+	p := NewPrefetcher(src, 4, 5, func(x interface{}) (interface{}, error) {
+		// Make later samples finish first.
+		time.Sleep(time.Duration(10-x.(int)%10) * 100 * time.Microsecond)
+		return x.(int) * 2, nil
+	})
+	defer p.Close()
+
+	var actual []interface{}
+	for len(actual) < len(expected) {
+		batch, err := p.NextBatch()
+		if err == io.EOF {
+			actual = append(actual, io.EOF)
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < batch.Len(); i++ {
+			actual = append(actual, batch.GetSample(i).(int)/2)
+		}
+	}
+	for i, x := range expected {
+		if actual[i] != x {
+			t.Fatalf("index %d: expected %v but got %v", i, x, actual[i])
+		}
+	}
+}
+
+func TestPrefetcherSourceError(t *testing.T) {
+	testErr := errors.New("test error")
+	src := &scriptTestSource{}
+	src.Add(SliceSampleSet{1}, nil)
+	src.Add(SliceSampleSet{2}, nil)
+	src.Add(nil, testErr)
+	src.Add(SliceSampleSet{3}, nil)
+	p := NewPrefetcher(src, 2, 1, nil)
+	defer p.Close()
+	for i := 1; i <= 2; i++ {
+		batch, err := p.NextBatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batch.GetSample(0) != i {
+			t.Errorf("expected sample %d but got %v", i, batch.GetSample(0))
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := p.NextBatch(); err != testErr {
+			t.Errorf("call %d: expected test error but got %v", i, err)
+		}
+	}
+}
+
+func TestPrefetcherPreprocessError(t *testing.T) {
+	testErr := errors.New("test error")
+	src := &scriptTestSource{}
+	for i := 0; i < 5; i++ {
+		src.Add(SliceSampleSet{i * 2, i*2 + 1}, nil)
+	}
+	p := NewPrefetcher(src, 3, 3, func(x interface{}) (interface{}, error) {
+		if x.(int) == 5 {
+			return nil, testErr
+		}
+		return x, nil
+	})
+	defer p.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := p.NextBatch(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := p.NextBatch(); err != testErr {
+			t.Errorf("call %d: expected test error but got %v", i, err)
+		}
+	}
+}
+
+func TestPrefetcherClose(t *testing.T) {
+	t.Run("Blocked", func(t *testing.T) {
+		src := &scriptTestSource{Block: make(chan struct{})}
+		p := NewPrefetcher(src, 2, 1, nil)
+		errs := make(chan error, 1)
+		go func() {
+			_, err := p.NextBatch()
+			errs <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		closed := make(chan struct{})
+		go func() {
+			p.Close()
+			close(closed)
+		}()
+		select {
+		case err := <-errs:
+			if err != ErrPrefetcherClosed {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("consumer was not unblocked")
+		}
+
+		// Close waits for the source to return.
+		close(src.Block)
+		select {
+		case <-closed:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Close did not return")
+		}
+	})
+
+	t.Run("Queued", func(t *testing.T) {
+		src := &scriptTestSource{Calls: make(chan struct{}, 10)}
+		for i := 0; i < 10; i++ {
+			src.Add(SliceSampleSet{i}, nil)
+		}
+		p := NewPrefetcher(src, 4, 1, nil)
+
+		// The fifth batch can only be read once the first
+		// four are queued, and nothing more can be read
+		// until the consumer makes room.
+		for i := 0; i < 5; i++ {
+			<-src.Calls
+		}
+		select {
+		case <-src.Calls:
+			t.Error("read more than size+1 batches ahead")
+		case <-time.After(10 * time.Millisecond):
+		}
+		p.Close()
+		for i := 0; i < 20; i++ {
+			if _, err := p.NextBatch(); err != ErrPrefetcherClosed {
+				t.Fatalf("call %d: expected ErrPrefetcherClosed but got %v", i, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package sgd
+
+// RandSource is a math/rand.Source64 whose state can be
+// saved and restored, making it possible to resume a
+// random sequence exactly.
+//
+// It implements the SplitMix64 generator, which is fast
+// and has a single 64-bit word of state.
+//
+// A RandSource is not safe to use from multiple
+// Goroutines at once.
+type RandSource struct {
+	state uint64
+}
+
+// NewRandSource creates a RandSource with the given seed.
+func NewRandSource(seed int64) *RandSource {
+	return &RandSource{state: uint64(seed)}
+}
+
+// Seed resets the source to the given seed.
+func (r *RandSource) Seed(seed int64) {
+	r.state = uint64(seed)
+}
+
+// Uint64 generates a random 64-bit integer.
+func (r *RandSource) Uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Int63 generates a random non-negative 63-bit integer.
+func (r *RandSource) Int63() int64 {
+	return int64(r.Uint64() >> 1)
+}
+
+// State returns the current state of the source.
+func (r *RandSource) State() uint64 {
+	return r.state
+}
+
+// SetState restores a state returned by State.
+func (r *RandSource) SetState(state uint64) {
+	r.state = state
+}
@@ -0,0 +1,331 @@
+package sgd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// The record file format is laid out as follows, with all
+// integers and floats in big-endian order:
+//
+//	header:  magic (8 bytes), version (uint32)
+//	records: for each record, the number of vectors
+//	         (uint32), followed by each vector's length
+//	         (uint64) and components (float64)
+//	index:   the offset of each record (uint64)
+//	footer:  index offset (uint64), record count
+//	         (uint64), flags (uint32), magic (8 bytes)
+//
+// The footer makes it possible to write a file in one
+// pass without knowing the number of records up front.
+const (
+	recordFileMagic      = "SGDRECS\x00"
+	recordFileVersion    = 1
+	recordFileHeaderSize = 12
+	recordFileFooterSize = 28
+
+	// recordFlagSingle indicates that every record holds
+	// one vector which should be decoded as a
+	// linalg.Vector rather than a []linalg.Vector.
+	recordFlagSingle = 1
+)
+
+var recordByteOrder = binary.BigEndian
+
+// WriteRecordFile creates a record file from a SampleSet.
+// See WriteRecords for details.
+func WriteRecordFile(path string, s SampleSet) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return WriteRecords(f, s)
+}
+
+// WriteRecords encodes the samples of a SampleSet in the
+// record file format, which can be read with
+// OpenRecordFile.
+//
+// Every sample must be a linalg.Vector, or every sample
+// must be a []linalg.Vector.
+// Samples read back from the file will have the same type.
+func WriteRecords(w io.Writer, s SampleSet) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(recordFileMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, recordByteOrder, uint32(recordFileVersion)); err != nil {
+		return err
+	}
+
+	var flags uint32
+	if s.Len() > 0 {
+		if _, ok := s.GetSample(0).(linalg.Vector); ok {
+			flags |= recordFlagSingle
+		}
+	}
+
+	offset := uint64(recordFileHeaderSize)
+	offsets := make([]uint64, s.Len())
+	for i := range offsets {
+		offsets[i] = offset
+		vecs, err := recordVectors(s.GetSample(i), flags)
+		if err != nil {
+			return fmt.Errorf("sample %d: %s", i, err)
+		}
+		size, err := writeRecord(bw, vecs)
+		if err != nil {
+			return err
+		}
+		offset += size
+	}
+
+	if err := binary.Write(bw, recordByteOrder, offsets); err != nil {
+		return err
+	}
+	footer := []interface{}{offset, uint64(len(offsets)), flags}
+	for _, x := range footer {
+		if err := binary.Write(bw, recordByteOrder, x); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.WriteString(recordFileMagic); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func recordVectors(sample interface{}, flags uint32) ([]linalg.Vector, error) {
+	if flags&recordFlagSingle != 0 {
+		if vec, ok := sample.(linalg.Vector); ok {
+			return []linalg.Vector{vec}, nil
+		}
+		return nil, errors.New("expected linalg.Vector")
+	}
+	if vecs, ok := sample.([]linalg.Vector); ok {
+		return vecs, nil
+	}
+	return nil, errors.New("expected []linalg.Vector")
+}
+
+func writeRecord(w io.Writer, vecs []linalg.Vector) (uint64, error) {
+	if err := binary.Write(w, recordByteOrder, uint32(len(vecs))); err != nil {
+		return 0, err
+	}
+	size := uint64(4)
+	for _, vec := range vecs {
+		if err := binary.Write(w, recordByteOrder, uint64(len(vec))); err != nil {
+			return 0, err
+		}
+		if err := binary.Write(w, recordByteOrder, []float64(vec)); err != nil {
+			return 0, err
+		}
+		size += 8 + 8*uint64(len(vec))
+	}
+	return size, nil
+}
+
+// A RecordFile provides random access to the records in a
+// record file without reading the whole file into memory.
+//
+// On most Unix systems, the file is memory-mapped, so the
+// operating system pages records in and out as needed.
+// On other systems, the file is read into memory.
+type RecordFile struct {
+	data  []byte
+	close func() error
+
+	indexOffset int
+	count       int
+	single      bool
+}
+
+// OpenRecordFile opens a file created by WriteRecords or
+// WriteRecordFile.
+//
+// The RecordFile should be closed when it is no longer
+// needed, after which none of its RecordSets may be used.
+func OpenRecordFile(path string) (*RecordFile, error) {
+	data, closeFunc, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := newRecordFile(data)
+	if err != nil {
+		closeFunc()
+		return nil, fmt.Errorf("open record file %s: %s", path, err)
+	}
+	res.close = closeFunc
+	return res, nil
+}
+
+func newRecordFile(data []byte) (*RecordFile, error) {
+	if len(data) < recordFileHeaderSize+recordFileFooterSize {
+		return nil, errors.New("file too small")
+	}
+	if string(data[:8]) != recordFileMagic ||
+		string(data[len(data)-8:]) != recordFileMagic {
+		return nil, errors.New("bad magic number")
+	}
+	if version := recordByteOrder.Uint32(data[8:]); version > recordFileVersion {
+		return nil, fmt.Errorf("unsupported version: %d", version)
+	}
+
+	footer := data[len(data)-recordFileFooterSize:]
+	indexOffset := recordByteOrder.Uint64(footer)
+	count := recordByteOrder.Uint64(footer[8:])
+	flags := recordByteOrder.Uint32(footer[16:])
+	footerStart := uint64(len(data) - recordFileFooterSize)
+	if indexOffset < recordFileHeaderSize || indexOffset > footerStart ||
+		count != (footerStart-indexOffset)/8 || (footerStart-indexOffset)%8 != 0 {
+		return nil, errors.New("corrupt index")
+	}
+
+	res := &RecordFile{
+		data:        data,
+		indexOffset: int(indexOffset),
+		count:       int(count),
+		single:      flags&recordFlagSingle != 0,
+	}
+	var lastOffset uint64
+	for i := 0; i < res.count; i++ {
+		offset := res.recordOffset(i)
+		if offset < lastOffset || offset < recordFileHeaderSize || offset >= indexOffset {
+			return nil, errors.New("corrupt index")
+		}
+		lastOffset = offset
+	}
+	return res, nil
+}
+
+// Close releases the file.
+func (r *RecordFile) Close() error {
+	if r.close == nil {
+		return nil
+	}
+	err := r.close()
+	r.close = nil
+	r.data = nil
+	return err
+}
+
+// Len returns the number of records in the file.
+func (r *RecordFile) Len() int {
+	return r.count
+}
+
+// Record decodes the record at the given index.
+//
+// The result is a linalg.Vector or a []linalg.Vector,
+// depending on what type of samples were written.
+//
+// Record panics if the record is corrupt.
+func (r *RecordFile) Record(idx int) interface{} {
+	vecs := r.decodeRecord(r.recordOffset(idx))
+	if r.single {
+		if len(vecs) != 1 {
+			panic("corrupt record file: expected one vector per record")
+		}
+		return vecs[0]
+	}
+	return vecs
+}
+
+// SampleSet creates a RecordSet containing every record
+// in the file, in order.
+func (r *RecordFile) SampleSet() *RecordSet {
+	perm := make([]int, r.count)
+	for i := range perm {
+		perm[i] = i
+	}
+	return &RecordSet{File: r, Indices: perm}
+}
+
+func (r *RecordFile) recordOffset(idx int) uint64 {
+	return recordByteOrder.Uint64(r.data[r.indexOffset+8*idx:])
+}
+
+func (r *RecordFile) decodeRecord(offset uint64) []linalg.Vector {
+	data := r.data[offset:r.indexOffset]
+	if len(data) < 4 {
+		panic("corrupt record file: truncated record")
+	}
+	count := recordByteOrder.Uint32(data)
+	data = data[4:]
+
+	// Every vector has an 8-byte size, so this bounds the
+	// allocation below by the size of the record.
+	if uint64(count) > uint64(len(data))/8 {
+		panic("corrupt record file: truncated record")
+	}
+	res := make([]linalg.Vector, 0, count)
+	for i := 0; i < int(count); i++ {
+		if len(data) < 8 {
+			panic("corrupt record file: truncated record")
+		}
+		size := recordByteOrder.Uint64(data)
+		data = data[8:]
+		if size > uint64(len(data))/8 {
+			panic("corrupt record file: truncated record")
+		}
+		vec := make(linalg.Vector, int(size))
+		for j := range vec {
+			vec[j] = math.Float64frombits(recordByteOrder.Uint64(data[8*j:]))
+		}
+		data = data[8*size:]
+		res = append(res, vec)
+	}
+	return res
+}
+
+// RecordSet is a SampleSet and Hasher backed by a
+// RecordFile.
+//
+// The samples are only decoded when they are accessed.
+// Swap, Copy, and Subset operate on a list of record
+// indices, so they never copy the underlying records.
+type RecordSet struct {
+	File *RecordFile
+
+	// Indices lists the records in the set, in order.
+	Indices []int
+}
+
+func (r *RecordSet) Len() int {
+	return len(r.Indices)
+}
+
+func (r *RecordSet) Copy() SampleSet {
+	return &RecordSet{File: r.File, Indices: append([]int{}, r.Indices...)}
+}
+
+func (r *RecordSet) Swap(i, j int) {
+	r.Indices[i], r.Indices[j] = r.Indices[j], r.Indices[i]
+}
+
+func (r *RecordSet) GetSample(idx int) interface{} {
+	return r.File.Record(r.Indices[idx])
+}
+
+func (r *RecordSet) Subset(start, end int) SampleSet {
+	return &RecordSet{File: r.File, Indices: r.Indices[start:end]}
+}
+
+// Hash hashes the sample at the given index with
+// HashVectors, so a RecordSet splits the same way as an
+// in-memory set of the same vectors.
+func (r *RecordSet) Hash(idx int) []byte {
+	return HashVectors(r.File.decodeRecord(r.File.recordOffset(r.Indices[idx]))...)
+}
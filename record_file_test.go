@@ -0,0 +1,106 @@
+package sgd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestRecordFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sgd_record_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "records")
+
+	samples := SliceSampleSet{
+		[]linalg.Vector{{1, 2, 3}, {4}},
+		[]linalg.Vector{{}, {-1, 0.5}},
+		[]linalg.Vector{},
+		[]linalg.Vector{{7, 7, 7, 7}},
+	}
+	if err := WriteRecordFile(path, samples); err != nil {
+		t.Fatal(err)
+	}
+	file, err := OpenRecordFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	set := file.SampleSet()
+	if set.Len() != samples.Len() {
+		t.Fatalf("expected %d records but got %d", samples.Len(), set.Len())
+	}
+	for i := 0; i < set.Len(); i++ {
+		expected := samples[i].([]linalg.Vector)
+		actual := set.GetSample(i).([]linalg.Vector)
+		if !vectorListsEqual(expected, actual) {
+			t.Errorf("record %d: expected %v but got %v", i, expected, actual)
+		}
+		if !bytes.Equal(set.Hash(i), HashVectors(expected...)) {
+			t.Errorf("record %d: unexpected hash", i)
+		}
+	}
+
+	subset := set.Subset(1, 3)
+	set.Swap(1, 2)
+	if !vectorListsEqual(subset.GetSample(0).([]linalg.Vector), samples[2].([]linalg.Vector)) {
+		t.Error("subset does not reflect swap")
+	}
+}
+
+func TestRecordFileCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, SliceSampleSet{linalg.Vector{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := newRecordFile(data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newRecordFile(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated file")
+	}
+}
+
+func TestRecordFileHugeCount(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, SliceSampleSet{[]linalg.Vector{{1, 2}}}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	file, err := newRecordFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordByteOrder.PutUint32(data[file.recordOffset(0):], 0xffffffff)
+	defer func() {
+		if r := recover(); r != "corrupt record file: truncated record" {
+			t.Errorf("unexpected panic: %v", r)
+		}
+	}()
+	file.Record(0)
+}
+
+func vectorListsEqual(v1, v2 []linalg.Vector) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i, vec := range v1 {
+		if len(vec) != len(v2[i]) {
+			return false
+		}
+		for j, x := range vec {
+			if v2[i][j] != x {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package sgd
 
 import (
+	"io"
 	"math"
 
 	"github.com/unixpickle/autofunc"
@@ -43,6 +44,9 @@ func (r *RMSProp) Gradient(s SampleSet) autofunc.Gradient {
 }
 
 func (r *RMSProp) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
 	squaredGrad := grad.Copy()
 	for _, v := range squaredGrad {
 		for i, x := range v {
@@ -73,3 +77,24 @@ func (r *RMSProp) Transform(grad autofunc.Gradient) autofunc.Gradient {
 
 	return grad
 }
+
+// SaveState saves the RollingAverage.
+func (r *RMSProp) SaveState(w io.Writer, params []*autofunc.Variable) error {
+	if err := writeStateHeader(w, "RMSProp"); err != nil {
+		return err
+	}
+	return writeStateGradient(w, r.RollingAverage, params)
+}
+
+// LoadState loads state saved with SaveState.
+func (r *RMSProp) LoadState(rd io.Reader, params []*autofunc.Variable) error {
+	if err := readStateHeader(rd, "RMSProp"); err != nil {
+		return err
+	}
+	avg, err := readStateGradient(rd, params)
+	if err != nil {
+		return err
+	}
+	r.RollingAverage = avg
+	return nil
+}
@@ -31,9 +31,24 @@ type SampleSet interface {
 	Subset(start, end int) SampleSet
 }
 
+// ShuffleSampleSet randomly reorders a SampleSet using
+// the global source from math/rand.
 func ShuffleSampleSet(s SampleSet) {
+	ShuffleSampleSetRand(s, nil)
+}
+
+// ShuffleSampleSetRand is like ShuffleSampleSet, but it
+// draws from r, or from the global source if r is nil.
+//
+// Shuffling the same SampleSet (in the same order) with
+// two sources in the same state yields the same order.
+func ShuffleSampleSetRand(s SampleSet, r *RandSource) {
+	intn := rand.Intn
+	if r != nil {
+		intn = rand.New(r).Intn
+	}
 	for i := 0; i < s.Len(); i++ {
-		j := i + rand.Intn(s.Len()-i)
+		j := i + intn(s.Len()-i)
 		s.Swap(i, j)
 	}
 }
@@ -46,10 +61,18 @@ func (s SliceSampleSet) Len() int {
 	return len(s)
 }
 
+// Copy returns a new slice with the same samples, so that
+// swapping samples in the copy does not reorder s.
+//
+// Older versions returned s itself, so that shuffling a
+// copy (as SGD and SGDMini do every epoch) shuffled the
+// caller's slice as well.
+// Code which relied on that side effect should shuffle s
+// directly.
 func (s SliceSampleSet) Copy() SampleSet {
 	res := make(SliceSampleSet, len(s))
 	copy(res, s)
-	return s
+	return res
 }
 
 func (s SliceSampleSet) Swap(i, j int) {
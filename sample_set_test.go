@@ -0,0 +1,15 @@
+package sgd
+
+import "testing"
+
+func TestSliceSampleSetCopy(t *testing.T) {
+	samples := SliceSampleSet{1, 2, 3}
+	c := samples.Copy()
+	c.Swap(0, 2)
+	if samples[0] != 1 || samples[2] != 3 {
+		t.Errorf("swapping the copy reordered the original: %v", samples)
+	}
+	if c.GetSample(0) != 3 || c.GetSample(2) != 1 {
+		t.Errorf("unexpected copy: %v", c)
+	}
+}
@@ -0,0 +1,200 @@
+package sgd
+
+import "math"
+
+const (
+	defaultOneCycleWarmup     = 0.3
+	defaultOneCycleInitialDiv = 25
+	defaultOneCycleFinalDiv   = 1e4
+)
+
+// A Schedule determines the step size to use at each
+// step of training.
+//
+// The step argument is the global number of steps taken
+// so far (starting at 0), and epoch is the number of full
+// sweeps over the sample set completed so far.
+type Schedule interface {
+	StepSize(step, epoch int) float64
+}
+
+// ConstantSchedule is a Schedule which always uses the
+// same step size.
+type ConstantSchedule float64
+
+func (c ConstantSchedule) StepSize(step, epoch int) float64 {
+	return float64(c)
+}
+
+// StepDecaySchedule multiplies the step size by a fixed
+// factor every few epochs.
+type StepDecaySchedule struct {
+	Initial float64
+
+	// Factor is multiplied into the step size after
+	// every Interval epochs.
+	Factor float64
+
+	// Interval is the number of epochs between decays.
+	// If it is 0, a value of 1 is used.
+	Interval int
+}
+
+func (s *StepDecaySchedule) StepSize(step, epoch int) float64 {
+	interval := s.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	return s.Initial * math.Pow(s.Factor, float64(epoch/interval))
+}
+
+// ExponentialSchedule multiplies the step size by a fixed
+// factor after every step.
+type ExponentialSchedule struct {
+	Initial float64
+	Decay   float64
+}
+
+func (e *ExponentialSchedule) StepSize(step, epoch int) float64 {
+	return e.Initial * math.Pow(e.Decay, float64(step))
+}
+
+// InvSqrtSchedule decays the step size proportionally to
+// the inverse square root of the step count.
+//
+// The step size at step t is Initial/sqrt(1+t/Timescale).
+type InvSqrtSchedule struct {
+	Initial float64
+
+	// Timescale controls how quickly the step size
+	// decays.
+	// If it is 0, a value of 1 is used.
+	Timescale float64
+}
+
+func (i *InvSqrtSchedule) StepSize(step, epoch int) float64 {
+	timescale := i.Timescale
+	if timescale == 0 {
+		timescale = 1
+	}
+	return i.Initial / math.Sqrt(1+float64(step)/timescale)
+}
+
+// CosineSchedule implements cosine annealing with warm
+// restarts, as described in
+// https://arxiv.org/abs/1608.03983.
+//
+// The step size follows half a cosine wave from Max to
+// Min over Period steps, then jumps back to Max.
+//
+// If Period is not positive, there is no annealing and the
+// step size is always Max.
+type CosineSchedule struct {
+	Max float64
+	Min float64
+
+	// Period is the number of steps in the first cycle.
+	Period int
+
+	// PeriodScale is multiplied into the period after
+	// every restart.
+	// If it is 0, a value of 1 is used.
+	//
+	// If it is less than 1, the cycles shrink until they
+	// are shorter than a single step, at which point the
+	// step size stays at Min.
+	PeriodScale float64
+}
+
+func (c *CosineSchedule) StepSize(step, epoch int) float64 {
+	if c.Period <= 0 {
+		return c.Max
+	}
+	scale := c.PeriodScale
+	if scale == 0 {
+		scale = 1
+	}
+	period := float64(c.Period)
+	t := float64(step)
+	if scale == 1 {
+		t = math.Mod(t, period)
+	}
+	for t >= period {
+		t -= period
+		period *= scale
+		if period < 1 {
+			return c.Min
+		}
+	}
+	return c.Min + 0.5*(c.Max-c.Min)*(1+math.Cos(math.Pi*t/period))
+}
+
+// WarmupSchedule linearly increases the step size from
+// zero to that of a wrapped Schedule over the first few
+// steps, then defers to the wrapped Schedule.
+type WarmupSchedule struct {
+	Schedule Schedule
+	Steps    int
+}
+
+func (w *WarmupSchedule) StepSize(step, epoch int) float64 {
+	res := w.Schedule.StepSize(step, epoch)
+	if step < w.Steps {
+		res *= float64(step+1) / float64(w.Steps)
+	}
+	return res
+}
+
+// OneCycleSchedule implements the 1cycle policy described
+// in https://arxiv.org/abs/1708.07120.
+//
+// The step size is annealed from Max/InitialDiv up to Max,
+// then down to Max/FinalDiv by the end of Steps steps.
+// Both phases use cosine annealing.
+// After Steps steps, the final step size is used.
+type OneCycleSchedule struct {
+	Max   float64
+	Steps int
+
+	// Warmup is the fraction of steps spent increasing
+	// the step size.
+	// If it is 0, a default of 0.3 is used.
+	Warmup float64
+
+	// InitialDiv and FinalDiv determine the first and
+	// last step sizes relative to Max.
+	// If they are 0, defaults of 25 and 1e4 are used.
+	InitialDiv float64
+	FinalDiv   float64
+}
+
+func (o *OneCycleSchedule) StepSize(step, epoch int) float64 {
+	warmup := o.Warmup
+	if warmup == 0 {
+		warmup = defaultOneCycleWarmup
+	}
+	initialDiv := o.InitialDiv
+	if initialDiv == 0 {
+		initialDiv = defaultOneCycleInitialDiv
+	}
+	finalDiv := o.FinalDiv
+	if finalDiv == 0 {
+		finalDiv = defaultOneCycleFinalDiv
+	}
+
+	upSteps := warmup * float64(o.Steps)
+	downSteps := float64(o.Steps) - upSteps
+	t := float64(step)
+	if t < upSteps {
+		return cosineInterp(o.Max/initialDiv, o.Max, t/upSteps)
+	} else if t < float64(o.Steps) {
+		return cosineInterp(o.Max, o.Max/finalDiv, (t-upSteps)/downSteps)
+	}
+	return o.Max / finalDiv
+}
+
+// cosineInterp moves from start to end along half of a
+// cosine wave as frac goes from 0 to 1.
+func cosineInterp(start, end, frac float64) float64 {
+	return end + 0.5*(start-end)*(1+math.Cos(math.Pi*frac))
+}
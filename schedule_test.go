@@ -0,0 +1,81 @@
+package sgd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineScheduleRestarts(t *testing.T) {
+	sched := &CosineSchedule{Max: 1, Min: 0.1, Period: 4, PeriodScale: 2}
+	expected := map[int]float64{
+		0:  1,
+		2:  0.55,
+		4:  1,
+		8:  0.55,
+		12: 1,
+	}
+	for step, x := range expected {
+		if a := sched.StepSize(step, 0); math.Abs(a-x) > 1e-8 {
+			t.Errorf("step %d: expected %f but got %f", step, x, a)
+		}
+	}
+}
+
+func TestCosineScheduleDegenerate(t *testing.T) {
+	scheds := []*CosineSchedule{
+		{Max: 1, Min: 0.1},
+		{Max: 1, Min: 0.1, Period: 8, PeriodScale: 0.5},
+		{Max: 1, Min: 0.1, Period: 4},
+	}
+	expected := []map[int]float64{
+		{0: 1, 100: 1},
+		{0: 1, 4: 0.55, 8: 1, 14: 1, 15: 0.1, 1e9: 0.1},
+		{2: 0.55, 4: 1, 1e9 + 2: 0.55},
+	}
+	for i, sched := range scheds {
+		for step, x := range expected[i] {
+			if a := sched.StepSize(step, 0); math.Abs(a-x) > 1e-8 {
+				t.Errorf("schedule %d: step %d: expected %f but got %f", i, step, x, a)
+			}
+		}
+	}
+}
+
+func TestOneCycleSchedule(t *testing.T) {
+	sched := &OneCycleSchedule{Max: 1, Steps: 10, Warmup: 0.5, InitialDiv: 10, FinalDiv: 100}
+	expected := map[int]float64{
+		0:  0.1,
+		5:  1,
+		10: 0.01,
+		20: 0.01,
+	}
+	for step, x := range expected {
+		if a := sched.StepSize(step, 0); math.Abs(a-x) > 1e-8 {
+			t.Errorf("step %d: expected %f but got %f", step, x, a)
+		}
+	}
+	last := sched.StepSize(5, 0)
+	for step := 6; step <= 10; step++ {
+		x := sched.StepSize(step, 0)
+		if x >= last {
+			t.Errorf("step %d: step size did not decrease", step)
+		}
+		last = x
+	}
+}
+
+func TestWarmupSchedule(t *testing.T) {
+	sched := &WarmupSchedule{
+		Schedule: &StepDecaySchedule{Initial: 2, Factor: 0.5, Interval: 2},
+		Steps:    4,
+	}
+	if x := sched.StepSize(0, 0); math.Abs(x-0.5) > 1e-8 {
+		t.Errorf("expected 0.5 but got %f", x)
+	}
+	if x := sched.StepSize(3, 0); math.Abs(x-2) > 1e-8 {
+		t.Errorf("expected 2 but got %f", x)
+	}
+	if x := sched.StepSize(100, 5); math.Abs(x-0.5) > 1e-8 {
+		t.Errorf("expected 0.5 but got %f", x)
+	}
+}
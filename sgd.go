@@ -4,6 +4,16 @@
 //
 // It includes many modified variants of SGD, such as
 // AdaGrad and RMSProp.
+//
+// By default, shuffling and other random choices draw
+// from the global source in math/rand.
+// Types with a Rand field (and functions taking a
+// *RandSource) use that source instead, in which case two
+// runs with the same inputs, the same seed, and the same
+// sequence of calls make identical random choices.
+// In particular, a Trainer with a Rand visits batches in
+// the same order, and an Equilibration with a Rand draws
+// the same preconditioner samples.
 package sgd
 
 // SGD performs stochastic gradient descent using the
@@ -0,0 +1,199 @@
+package sgd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// These errors are returned by the context-aware training
+// loops to indicate why training stopped.
+var (
+	ErrEpochLimit = errors.New("epoch limit reached")
+	ErrStepLimit  = errors.New("step limit reached")
+	ErrTimeLimit  = errors.New("time limit reached")
+	ErrStopped    = errors.New("stopped by callback")
+)
+
+// A Budget limits the amount of work a training loop may
+// do before it stops.
+//
+// A zero field imposes no limit.
+// A zero Budget therefore allows a loop to run until it
+// is cancelled or stopped by a callback.
+type Budget struct {
+	// Epochs is the maximum number of full sweeps over
+	// the sample set.
+	Epochs int
+
+	// Steps is the maximum number of parameter updates.
+	// This is the number of mini-batches, unless a
+	// Gradienter like Accumulator combines several
+	// mini-batches into one update.
+	Steps int
+
+	// Duration is the maximum amount of wall-clock time
+	// to train for.
+	// Training stops between mini-batches, so a long
+	// mini-batch may make a loop overshoot slightly.
+	Duration time.Duration
+}
+
+// LoopOptions contains optional settings for the
+// context-aware training loops.
+// A nil *LoopOptions is equivalent to a zero LoopOptions.
+type LoopOptions struct {
+	// Rand, if non-nil, is used to shuffle the samples, as
+	// with Trainer.Rand.
+	// Otherwise, the global source from math/rand is used.
+	Rand *RandSource
+
+	// Events, if non-nil, receives the events of the
+	// underlying Trainer.
+	Events EventSink
+}
+
+// SGDContext is like SGD, but it runs until ctx is done or
+// the Budget is exhausted.
+//
+// The returned error is never nil.
+// It is ctx.Err() if the context ended training, or one
+// of ErrEpochLimit, ErrStepLimit, or ErrTimeLimit if the
+// budget did.
+//
+// Unlike SGDInteractive, this never installs a signal
+// handler or prints anything.
+// See InterruptContext for a way to stop on os.Interrupt.
+func SGDContext(ctx context.Context, g Gradienter, s SampleSet, stepSize float64,
+	batchSize int, b Budget, o *LoopOptions) error {
+	return contextLoop(ctx, g, s, ConstantSchedule(stepSize), batchSize, b, o, nil, nil)
+}
+
+// SGDSchedule is like SGDContext, but it takes the step
+// size for each mini-batch from a Schedule.
+func SGDSchedule(ctx context.Context, g Gradienter, s SampleSet, sched Schedule,
+	batchSize int, b Budget, o *LoopOptions) error {
+	return contextLoop(ctx, g, s, sched, batchSize, b, o, nil, nil)
+}
+
+// SGDInteractiveContext is like SGDSchedule, but it calls
+// sf before each epoch and stops with ErrStopped when sf
+// returns false.
+//
+// As with SGDInteractive, sf may modify the SampleSet for
+// the next epoch.
+// For a fixed step size, use a ConstantSchedule.
+func SGDInteractiveContext(ctx context.Context, g Gradienter, s SampleSet, sched Schedule,
+	batchSize int, b Budget, o *LoopOptions, sf func() bool) error {
+	return contextLoop(ctx, g, s, sched, batchSize, b, o, sf, nil)
+}
+
+// SGDMiniContext is like SGDSchedule, but it calls sf with
+// each mini-batch before training on it and stops with
+// ErrStopped when sf returns false.
+// For a fixed step size, use a ConstantSchedule.
+func SGDMiniContext(ctx context.Context, g Gradienter, s SampleSet, sched Schedule,
+	batchSize int, b Budget, o *LoopOptions, sf func(batch SampleSet) bool) error {
+	return contextLoop(ctx, g, s, sched, batchSize, b, o, nil, sf)
+}
+
+// SGDMiniStream is like SGDMiniContext, but it trains on
+// mini-batches from a BatchSource, allowing for data sets
+// which do not fit in memory.
+//
+// Each io.EOF from the BatchSource counts as the end of an
+// epoch for the purposes of the Budget and the Schedule.
+//
+// Since the BatchSource determines the order of the
+// samples, the Rand option is ignored.
+func SGDMiniStream(ctx context.Context, g Gradienter, src BatchSource, sched Schedule,
+	b Budget, o *LoopOptions, sf func(batch SampleSet) bool) error {
+	t := &Trainer{
+		Gradienter: g,
+		Batches:    src,
+		Schedule:   sched,
+		Budget:     b,
+	}
+	if o != nil {
+		t.Events = o.Events
+	}
+	if sf != nil {
+		t.Hooks = []Hook{&HookFuncs{
+			BeforeBatchFunc: func(info *StepInfo) error {
+				if !sf(info.Batch) {
+					return ErrStopped
+				}
+				return nil
+			},
+		}}
+	}
+	return t.Run(ctx)
+}
+
+func contextLoop(ctx context.Context, g Gradienter, s SampleSet, sched Schedule,
+	batchSize int, b Budget, o *LoopOptions, epochFunc func() bool,
+	batchFunc func(SampleSet) bool) error {
+	t := &Trainer{
+		Gradienter: g,
+		Samples:    s,
+		BatchSize:  batchSize,
+		Schedule:   sched,
+		Budget:     b,
+	}
+	if o != nil {
+		t.Rand = o.Rand
+		t.Events = o.Events
+	}
+	if epochFunc != nil || batchFunc != nil {
+		t.Hooks = []Hook{&HookFuncs{
+			BeforeEpochFunc: func(info *StepInfo) error {
+				if epochFunc != nil && !epochFunc() {
+					return ErrStopped
+				}
+				return nil
+			},
+			BeforeBatchFunc: func(info *StepInfo) error {
+				if batchFunc != nil && !batchFunc(info.Batch) {
+					return ErrStopped
+				}
+				return nil
+			},
+		}}
+	}
+	return t.Run(ctx)
+}
+
+type budgetTracker struct {
+	budget   Budget
+	deadline time.Time
+	epochs   int
+	steps    int
+}
+
+func newBudgetTracker(b Budget) *budgetTracker {
+	res := &budgetTracker{budget: b}
+	if b.Duration != 0 {
+		res.deadline = time.Now().Add(b.Duration)
+	}
+	return res
+}
+
+func (b *budgetTracker) checkEpoch(ctx context.Context) error {
+	if b.budget.Epochs != 0 && b.epochs >= b.budget.Epochs {
+		return ErrEpochLimit
+	}
+	return b.checkStep(ctx)
+}
+
+func (b *budgetTracker) checkStep(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.budget.Steps != 0 && b.steps >= b.budget.Steps {
+		return ErrStepLimit
+	}
+	if !b.deadline.IsZero() && !time.Now().Before(b.deadline) {
+		return ErrTimeLimit
+	}
+	return nil
+}
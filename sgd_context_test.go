@@ -0,0 +1,193 @@
+package sgd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// countTestGradienter counts the mini-batches it sees and
+// calls a function after each one.
+type countTestGradienter struct {
+	Vars    []*autofunc.Variable
+	Batches int
+	Samples int
+	After   func()
+}
+
+func (c *countTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	c.Batches++
+	c.Samples += s.Len()
+	if c.After != nil {
+		c.After()
+	}
+	return autofunc.NewGradient(c.Vars)
+}
+
+func TestSGDContextErrors(t *testing.T) {
+	samples := SliceSampleSet{1.0, 2.0, 3.0, 4.0, 5.0}
+	vars := []*autofunc.Variable{{Vector: []float64{1, 2}}}
+
+	t.Run("EpochLimit", func(t *testing.T) {
+		g := &countTestGradienter{Vars: vars}
+		err := SGDContext(context.Background(), g, samples, 0.1, 2, Budget{Epochs: 2}, nil)
+		if err != ErrEpochLimit {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if g.Batches != 6 || g.Samples != 10 {
+			t.Errorf("expected 6 batches and 10 samples but got %d and %d",
+				g.Batches, g.Samples)
+		}
+	})
+
+	t.Run("StepLimit", func(t *testing.T) {
+		g := &countTestGradienter{Vars: vars}
+		err := SGDContext(context.Background(), g, samples, 0.1, 2,
+			Budget{Steps: 7, Epochs: 3}, nil)
+		if err != ErrStepLimit {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if g.Batches != 7 {
+			t.Errorf("expected 7 batches but got %d", g.Batches)
+		}
+	})
+
+	t.Run("TimeLimit", func(t *testing.T) {
+		g := &countTestGradienter{Vars: vars, After: func() {
+			time.Sleep(time.Millisecond)
+		}}
+		err := SGDContext(context.Background(), g, samples, 0.1, 2,
+			Budget{Duration: 20 * time.Millisecond}, nil)
+		if err != ErrTimeLimit {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if g.Batches == 0 {
+			t.Error("expected some batches before the time limit")
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		g := &countTestGradienter{Vars: vars}
+		g.After = func() {
+			if g.Batches == 4 {
+				cancel()
+			}
+		}
+		err := SGDContext(ctx, g, samples, 0.1, 2, Budget{Epochs: 10, Steps: 100}, nil)
+		if err != context.Canceled {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if g.Batches != 4 {
+			t.Errorf("expected 4 batches but got %d", g.Batches)
+		}
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		g := &countTestGradienter{Vars: vars, After: func() {
+			time.Sleep(time.Millisecond)
+		}}
+		err := SGDContext(ctx, g, samples, 0.1, 2, Budget{Duration: time.Hour}, nil)
+		if err != context.DeadlineExceeded {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		g := &countTestGradienter{Vars: vars}
+		var batches int
+		err := SGDMiniContext(context.Background(), g, samples, ConstantSchedule(0.1), 2,
+			Budget{}, nil, func(batch SampleSet) bool {
+				batches++
+				return batches < 3
+			})
+		if err != ErrStopped {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if g.Batches != 2 {
+			t.Errorf("expected 2 batches but got %d", g.Batches)
+		}
+	})
+}
+
+func TestSGDKeepsSampleOrder(t *testing.T) {
+	samples := SliceSampleSet{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0}
+	g := &countTestGradienter{Vars: []*autofunc.Variable{{Vector: []float64{1}}}}
+	SGD(g, samples, 0.1, 3, 2)
+	for i, x := range samples {
+		if x != float64(i+1) {
+			t.Fatalf("samples were reordered: %v", samples)
+		}
+	}
+}
+
+func TestSGDContextSeed(t *testing.T) {
+	samples := SliceSampleSet{}
+	for i := 0; i < 20; i++ {
+		samples = append(samples, float64(i))
+	}
+	batchOrder := func(seed int64) []float64 {
+		var res []float64
+		g := &countTestGradienter{Vars: []*autofunc.Variable{{Vector: []float64{1}}}}
+		SGDMiniContext(context.Background(), g, samples, ConstantSchedule(0.1), 3,
+			Budget{Epochs: 3}, &LoopOptions{Rand: NewRandSource(seed)},
+			func(batch SampleSet) bool {
+				for i := 0; i < batch.Len(); i++ {
+					res = append(res, batch.GetSample(i).(float64))
+				}
+				return true
+			})
+		return res
+	}
+	first := batchOrder(42)
+	if len(first) != 60 {
+		t.Fatalf("expected 60 samples but got %d", len(first))
+	}
+	for i, x := range batchOrder(42) {
+		if x != first[i] {
+			t.Fatalf("index %d: expected %f but got %f", i, first[i], x)
+		}
+	}
+	var differs bool
+	for i, x := range batchOrder(43) {
+		if x != first[i] {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Error("different seeds gave the same order")
+	}
+}
+
+func TestSGDContextEvents(t *testing.T) {
+	sink := &MemorySink{}
+	g := &countTestGradienter{Vars: []*autofunc.Variable{{Vector: []float64{1}}}}
+	samples := SliceSampleSet{1.0, 2.0, 3.0}
+	err := SGDContext(context.Background(), g, samples, 0.1, 2, Budget{Epochs: 2},
+		&LoopOptions{Events: sink})
+	if err != ErrEpochLimit {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var types []string
+	for _, e := range sink.Events() {
+		types = append(types, e.EventType())
+	}
+	expected := []string{"step", "step", "epoch", "step", "step", "epoch", "stop"}
+	if len(types) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, types)
+	}
+	for i, x := range expected {
+		if types[i] != x {
+			t.Fatalf("expected %v but got %v", expected, types)
+		}
+	}
+	stop := sink.Events()[len(types)-1].(*StopEvent)
+	if stop.Step != 4 || stop.Epoch != 2 || stop.Reason != ErrEpochLimit.Error() {
+		t.Errorf("unexpected stop event: %+v", stop)
+	}
+}
@@ -1,10 +1,15 @@
 package sgd
 
+import "fmt"
+
 // SGDInteractive is like SGD, but it calls sf before
 // each epoch and stops when sf returns false.
 //
 // On platforms which support interrupts, it also stops
-// for an os.Interrupt signal.
+// for an os.Interrupt signal, printing a message to
+// standard output.
+// For typed events instead of printed messages, use
+// SGDInteractiveContext with InterruptContext.
 //
 // Calling sf may modify the SampleSet for the next epoch,
 // allowing for a dynamic set of samples (provided that
@@ -12,7 +17,7 @@ package sgd
 func SGDInteractive(g Gradienter, s SampleSet, stepSize float64, batchSize int, sf func() bool) {
 	loopUntilKilled(sf, func() {
 		SGD(g, s, stepSize, 1, batchSize)
-	})
+	}, printInterrupt)
 }
 
 // SGDMini is like SGDInteractive, but the interactive
@@ -41,5 +46,9 @@ func SGDMini(g Gradienter, s SampleSet, stepSize float64, batchSize int,
 	}, func() {
 		grad := g.Gradient(subset)
 		grad.AddToVars(-stepSize)
-	})
+	}, printInterrupt)
+}
+
+func printInterrupt() {
+	fmt.Println("\nCaught interrupt. Ctrl+C again to terminate.")
 }
@@ -0,0 +1,194 @@
+package sgd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// stateVersion is the version of the binary format used
+// by SaveState and LoadState.
+// It should be incremented whenever the format changes in
+// a way that older readers cannot handle.
+const stateVersion = 1
+
+var stateByteOrder = binary.BigEndian
+
+var errStateDimensions = errors.New("state does not match parameter dimensions")
+
+// A StateSaver is anything with internal state (such as an
+// optimizer's moment estimates) which can be saved and
+// loaded later to resume training.
+//
+// The state refers to parameters by their index in the
+// params slice rather than by pointer, so a state saved in
+// one process can be loaded into freshly created
+// variables in another, provided that the parameters are
+// listed in the same order and have the same sizes.
+//
+// Exported configuration fields are not part of the state,
+// and should be set up before calling LoadState.
+type StateSaver interface {
+	SaveState(w io.Writer, params []*autofunc.Variable) error
+	LoadState(r io.Reader, params []*autofunc.Variable) error
+}
+
+func writeStateHeader(w io.Writer, name string) error {
+	if err := binary.Write(w, stateByteOrder, uint32(stateVersion)); err != nil {
+		return err
+	}
+	return writeStateString(w, name)
+}
+
+func readStateHeader(r io.Reader, name string) error {
+	var version uint32
+	if err := binary.Read(r, stateByteOrder, &version); err != nil {
+		return err
+	}
+	if version == 0 || version > stateVersion {
+		return fmt.Errorf("unsupported state version: %d", version)
+	}
+	actualName, err := readStateString(r)
+	if err != nil {
+		return err
+	}
+	if actualName != name {
+		return fmt.Errorf("expected %s state but got %s state", name, actualName)
+	}
+	return nil
+}
+
+func writeStateString(w io.Writer, s string) error {
+	if err := writeStateInt(w, len(s)); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(s))
+	return err
+}
+
+func readStateString(r io.Reader) (string, error) {
+	size, err := readStateInt(r)
+	if err != nil {
+		return "", err
+	}
+	if size < 0 || size > 0xffff {
+		return "", errors.New("invalid string length")
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func writeStateInt(w io.Writer, x int) error {
+	return binary.Write(w, stateByteOrder, int64(x))
+}
+
+func readStateInt(r io.Reader) (int, error) {
+	var x int64
+	err := binary.Read(r, stateByteOrder, &x)
+	return int(x), err
+}
+
+func writeStateFloat(w io.Writer, x float64) error {
+	return binary.Write(w, stateByteOrder, x)
+}
+
+func readStateFloat(r io.Reader) (float64, error) {
+	var x float64
+	err := binary.Read(r, stateByteOrder, &x)
+	return x, err
+}
+
+func writeStateBool(w io.Writer, b bool) error {
+	var x uint8
+	if b {
+		x = 1
+	}
+	return binary.Write(w, stateByteOrder, x)
+}
+
+func readStateBool(r io.Reader) (bool, error) {
+	var x uint8
+	err := binary.Read(r, stateByteOrder, &x)
+	return x != 0, err
+}
+
+// writeStateGradient encodes the entries of a gradient
+// (which may be nil) in the order of params.
+// Each vector is preceded by its length, so that a state
+// for differently sized parameters can be detected.
+func writeStateGradient(w io.Writer, g map[*autofunc.Variable]linalg.Vector,
+	params []*autofunc.Variable) error {
+	if err := writeStateBool(w, g != nil); err != nil || g == nil {
+		return err
+	}
+	for _, p := range params {
+		vec, ok := g[p]
+		if err := writeStateBool(w, ok); err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if len(vec) != len(p.Vector) {
+			return errStateDimensions
+		}
+		if err := writeStateInt(w, len(vec)); err != nil {
+			return err
+		}
+		if err := binary.Write(w, stateByteOrder, []float64(vec)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readStateGradient decodes a gradient written by
+// writeStateGradient.
+// It returns nil if a nil gradient was written.
+func readStateGradient(r io.Reader, params []*autofunc.Variable) (map[*autofunc.Variable]linalg.Vector,
+	error) {
+	present, err := readStateBool(r)
+	if err != nil || !present {
+		return nil, err
+	}
+	res := map[*autofunc.Variable]linalg.Vector{}
+	for _, p := range params {
+		ok, err := readStateBool(r)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		size, err := readStateInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if size != len(p.Vector) {
+			return nil, errStateDimensions
+		}
+		vec := make(linalg.Vector, size)
+		if err := binary.Read(r, stateByteOrder, []float64(vec)); err != nil {
+			return nil, err
+		}
+		res[p] = vec
+	}
+	return res, nil
+}
+
+// saveStateless and loadStateless implement the state
+// format for Transformers which have no state.
+func saveStateless(w io.Writer, name string) error {
+	return writeStateHeader(w, name)
+}
+
+func loadStateless(r io.Reader, name string) error {
+	return readStateHeader(r, name)
+}
@@ -0,0 +1,214 @@
+package sgd
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+)
+
+var errInvalidBatchSize = errors.New("batch size must be positive")
+
+// A SampleStream produces samples one at a time, for data
+// sets which are too large to be accessed at random.
+type SampleStream interface {
+	// NextSample returns the next sample, or io.EOF if
+	// there are no more samples.
+	NextSample() (interface{}, error)
+}
+
+// A BatchSource produces mini-batches one at a time.
+//
+// A BatchSource is divided into epochs.
+// NextBatch returns io.EOF at the end of each epoch, and
+// the call after that starts a new epoch.
+type BatchSource interface {
+	NextBatch() (SampleSet, error)
+}
+
+// SampleSetStream creates a SampleStream which produces
+// the samples of a SampleSet in order.
+//
+// The SampleSet should not be modified while the stream
+// is in use.
+func SampleSetStream(s SampleSet) SampleStream {
+	return &sampleSetStream{samples: s}
+}
+
+type sampleSetStream struct {
+	samples SampleSet
+	idx     int
+}
+
+func (s *sampleSetStream) NextSample() (interface{}, error) {
+	if s.idx >= s.samples.Len() {
+		return nil, io.EOF
+	}
+	s.idx++
+	return s.samples.GetSample(s.idx - 1), nil
+}
+
+// ShuffleStream approximately shuffles a SampleStream by
+// reading samples into a buffer and producing them in a
+// random order.
+//
+// A sample can only be moved as far as the buffer allows,
+// so streams with an inherent order (e.g. sorted by
+// label) need a large buffer to be mixed well.
+type ShuffleStream struct {
+	Stream SampleStream
+
+	// BufferSize is the maximum number of samples to
+	// hold in memory at once.
+	BufferSize int
+
+	// Rand, if non-nil, is used to pick samples from the
+	// buffer.
+	// Otherwise, the global source from math/rand is used.
+	Rand *RandSource
+
+	buffer []interface{}
+	done   bool
+	gen    *rand.Rand
+}
+
+// NextSample returns a random sample from the buffer,
+// first filling the buffer if possible.
+func (s *ShuffleStream) NextSample() (interface{}, error) {
+	for !s.done && len(s.buffer) < s.BufferSize {
+		sample, err := s.Stream.NextSample()
+		if err == io.EOF {
+			s.done = true
+		} else if err != nil {
+			return nil, err
+		} else {
+			s.buffer = append(s.buffer, sample)
+		}
+	}
+	if len(s.buffer) == 0 {
+		if s.BufferSize == 0 {
+			return s.Stream.NextSample()
+		}
+		return nil, io.EOF
+	}
+	var idx int
+	if s.Rand == nil {
+		idx = rand.Intn(len(s.buffer))
+	} else {
+		if s.gen == nil {
+			s.gen = rand.New(s.Rand)
+		}
+		idx = s.gen.Intn(len(s.buffer))
+	}
+	last := len(s.buffer) - 1
+	res := s.buffer[idx]
+	s.buffer[idx] = s.buffer[last]
+	s.buffer[last] = nil
+	s.buffer = s.buffer[:last]
+	return res, nil
+}
+
+// StreamBatcher is a BatchSource which reads mini-batches
+// from SampleStreams, opening a new stream for each epoch.
+type StreamBatcher struct {
+	// Open creates the stream for the next epoch.
+	Open func() (SampleStream, error)
+
+	BatchSize int
+
+	// ShuffleBuffer, if non-zero, is the buffer size of a
+	// ShuffleStream to wrap around each stream.
+	ShuffleBuffer int
+
+	// Rand is used by the ShuffleStream.
+	Rand *RandSource
+
+	stream SampleStream
+	ended  bool
+}
+
+// NextBatch reads the next mini-batch.
+//
+// The last mini-batch of an epoch may be smaller than
+// BatchSize.
+// An error is returned if BatchSize is not positive.
+func (s *StreamBatcher) NextBatch() (SampleSet, error) {
+	if s.BatchSize <= 0 {
+		return nil, errInvalidBatchSize
+	}
+	if s.ended {
+		s.ended = false
+		s.stream = nil
+		return nil, io.EOF
+	}
+	if s.stream == nil {
+		stream, err := s.Open()
+		if err != nil {
+			return nil, err
+		}
+		if s.ShuffleBuffer != 0 {
+			stream = &ShuffleStream{
+				Stream:     stream,
+				BufferSize: s.ShuffleBuffer,
+				Rand:       s.Rand,
+			}
+		}
+		s.stream = stream
+	}
+	batch := make(SliceSampleSet, 0, s.BatchSize)
+	for len(batch) < s.BatchSize {
+		sample, err := s.stream.NextSample()
+		if err == io.EOF {
+			s.ended = true
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		batch = append(batch, sample)
+	}
+	if len(batch) == 0 {
+		return s.NextBatch()
+	}
+	return batch, nil
+}
+
+// SetBatcher is a BatchSource which produces mini-batches
+// from a shuffled copy of a SampleSet.
+// It reshuffles the samples at the start of each epoch.
+type SetBatcher struct {
+	Samples   SampleSet
+	BatchSize int
+
+	// Rand, if non-nil, is used for shuffling.
+	// Otherwise, the global source from math/rand is used.
+	Rand *RandSource
+
+	shuffled SampleSet
+	idx      int
+}
+
+// NextBatch returns the next mini-batch.
+//
+// The last mini-batch of an epoch may be smaller than
+// BatchSize.
+// An error is returned if BatchSize is not positive.
+func (s *SetBatcher) NextBatch() (SampleSet, error) {
+	if s.BatchSize <= 0 {
+		return nil, errInvalidBatchSize
+	}
+	if s.shuffled == nil {
+		s.shuffled = s.Samples.Copy()
+		ShuffleSampleSetRand(s.shuffled, s.Rand)
+		s.idx = 0
+	}
+	if s.idx >= s.shuffled.Len() {
+		s.shuffled = nil
+		return nil, io.EOF
+	}
+	end := s.idx + s.BatchSize
+	if end > s.shuffled.Len() {
+		end = s.shuffled.Len()
+	}
+	res := s.shuffled.Subset(s.idx, end)
+	s.idx = end
+	return res, nil
+}
@@ -0,0 +1,123 @@
+package sgd
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestStreamBatcher(t *testing.T) {
+	samples := SliceSampleSet{}
+	for i := 0; i < 10; i++ {
+		samples = append(samples, i)
+	}
+	batcher := &StreamBatcher{
+		Open: func() (SampleStream, error) {
+			return SampleSetStream(samples), nil
+		},
+		BatchSize:     4,
+		ShuffleBuffer: 3,
+		Rand:          NewRandSource(1),
+	}
+	for epoch := 0; epoch < 2; epoch++ {
+		seen := map[int]bool{}
+		var sizes []int
+		for {
+			batch, err := batcher.NextBatch()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			sizes = append(sizes, batch.Len())
+			for i := 0; i < batch.Len(); i++ {
+				x := batch.GetSample(i).(int)
+				if seen[x] {
+					t.Fatalf("epoch %d: duplicate sample %d", epoch, x)
+				}
+				seen[x] = true
+			}
+		}
+		if len(seen) != samples.Len() {
+			t.Errorf("epoch %d: saw %d samples", epoch, len(seen))
+		}
+		if len(sizes) != 3 || sizes[0] != 4 || sizes[1] != 4 || sizes[2] != 2 {
+			t.Errorf("epoch %d: unexpected batch sizes %v", epoch, sizes)
+		}
+	}
+}
+
+func TestStreamBatcherInvalid(t *testing.T) {
+	batcher := &StreamBatcher{
+		Open: func() (SampleStream, error) {
+			return SampleSetStream(SliceSampleSet{1, 2, 3}), nil
+		},
+	}
+	if _, err := batcher.NextBatch(); err == nil {
+		t.Error("expected error for zero batch size")
+	}
+	setBatcher := &SetBatcher{Samples: SliceSampleSet{1, 2, 3}}
+	if _, err := setBatcher.NextBatch(); err == nil {
+		t.Error("expected error for zero batch size")
+	}
+}
+
+func TestTrainerEmptyEpoch(t *testing.T) {
+	trainer := &Trainer{
+		Gradienter: zeroTestGradienter{},
+		Batches: &StreamBatcher{
+			Open: func() (SampleStream, error) {
+				return SampleSetStream(SliceSampleSet{}), nil
+			},
+			BatchSize: 2,
+		},
+		Schedule: ConstantSchedule(0.1),
+	}
+	if err := trainer.Run(context.Background()); err != ErrEmptyEpoch {
+		t.Errorf("expected ErrEmptyEpoch but got %v", err)
+	}
+	trainer.Batches = nil
+	trainer.Samples = SliceSampleSet{}
+	trainer.BatchSize = 2
+	if err := trainer.Run(context.Background()); err != ErrEmptyEpoch {
+		t.Errorf("expected ErrEmptyEpoch but got %v", err)
+	}
+}
+
+func TestTrainerPendingBatch(t *testing.T) {
+	src := &scriptTestSource{}
+	for i := 0; i < 3; i++ {
+		src.Add(SliceSampleSet{float64(i)}, nil)
+	}
+	var seen []interface{}
+	stop := true
+	trainer := &Trainer{
+		Gradienter: zeroTestGradienter{},
+		Batches:    src,
+		Schedule:   ConstantSchedule(0.1),
+		Budget:     Budget{Epochs: 1},
+		Hooks: []Hook{
+			&HookFuncs{
+				BeforeBatchFunc: func(info *StepInfo) error {
+					seen = append(seen, info.Batch.GetSample(0))
+					if stop {
+						stop = false
+						return ErrStopped
+					}
+					return nil
+				},
+			},
+		},
+	}
+	if err := trainer.Run(context.Background()); err != ErrStopped {
+		t.Fatalf("expected ErrStopped but got %v", err)
+	}
+	if err := trainer.Run(context.Background()); err != ErrEpochLimit {
+		t.Fatalf("expected ErrEpochLimit but got %v", err)
+	}
+	expected := []interface{}{0.0, 0.0, 1.0, 2.0}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("expected batches %v but got %v", expected, seen)
+	}
+}
@@ -0,0 +1,280 @@
+package sgd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/unixpickle/autofunc"
+)
+
+// These errors are returned by Trainer.Run when the
+// Trainer is not configured correctly.
+var (
+	ErrNoSchedule  = errors.New("trainer has no schedule")
+	ErrNoBatchSize = errors.New("trainer has no batch size")
+)
+
+// ErrEmptyEpoch is returned by Trainer.Run when an epoch
+// contains no mini-batches, and by SAGA.Run when there are
+// no samples, since training would otherwise loop forever
+// without doing anything.
+var ErrEmptyEpoch = errors.New("epoch has no batches")
+
+// A Trainer runs SGD on a Gradienter and notifies a list
+// of Hooks as training progresses.
+//
+// Hooks make it possible to compose logging, evaluation,
+// checkpointing, and the like without writing a custom
+// training loop.
+type Trainer struct {
+	Gradienter Gradienter
+	Samples    SampleSet
+	BatchSize  int
+
+	// Batches, if non-nil, is used to produce mini-batches
+	// instead of Samples and BatchSize.
+	// Each io.EOF from Batches ends an epoch.
+	//
+	// Since a BatchSource cannot be rewound, stopping in
+	// the middle of an epoch and restoring a Checkpoint
+	// does not replay the same batches.
+	Batches BatchSource
+
+	// Schedule determines the step size for each step.
+	// It is required.
+	Schedule Schedule
+
+	// LineSearch, if non-nil, is used to choose the step
+	// size for each batch, in which case the step size
+	// from Schedule is only used as the first guess.
+	// The search is along the negative of the gradient
+	// returned by Gradienter.
+	//
+	// For deterministic full-batch optimization, set
+	// BatchSize to the number of samples.
+	LineSearch LineSearcher
+
+	// Budget limits how long each call to Run may train.
+	Budget Budget
+
+	// Hooks are called in order at each stage of
+	// training.
+	Hooks []Hook
+
+	// Events, if non-nil, receives a StepEvent for every
+	// parameter update, an EpochEvent for every epoch, and
+	// a StopEvent when Run returns.
+	Events EventSink
+
+	// Rand, if non-nil, is used to shuffle the samples.
+	// Otherwise, the global source from math/rand is used.
+	//
+	// Each epoch shuffles a fresh copy of Samples, so the
+	// order of an epoch depends only on Samples and on the
+	// state of Rand when the epoch begins.
+	Rand *RandSource
+
+	// Step and Epoch count the steps and complete epochs
+	// performed so far, and EpochStep counts the batches
+	// processed in the current epoch.
+	// They are updated by Run and are used by Schedule,
+	// so calling Run again continues where the last call
+	// left off.
+	//
+	// A step is only counted when the parameters are
+	// updated, so batches for which the Gradienter returns
+	// nil (see Accumulator) do not advance Step.
+	Step      int
+	Epoch     int
+	EpochStep int
+
+	// epochRandState is the state of Rand at the start of
+	// the current epoch.
+	epochRandState uint64
+
+	// pendingBatch is a batch that was read from Batches
+	// but not used because training stopped.
+	pendingBatch SampleSet
+}
+
+// Run trains until ctx is done, the Budget is exhausted,
+// or a hook returns an error.
+//
+// The returned error is never nil, and is the same error
+// that is passed to each hook's OnStop method.
+// See SGDContext for the errors which indicate that the
+// context or budget ended training.
+//
+// If a previous call to Run stopped in the middle of an
+// epoch, the remainder of that epoch is trained first.
+// When Rand is set, the remainder uses the same sample
+// order that the interrupted epoch would have used.
+//
+// If Schedule is nil, or if BatchSize is not positive
+// when Batches is nil, Run returns ErrNoSchedule or
+// ErrNoBatchSize without calling any hooks.
+func (t *Trainer) Run(ctx context.Context) (err error) {
+	if t.Schedule == nil {
+		return ErrNoSchedule
+	}
+	if t.Batches == nil && t.BatchSize <= 0 {
+		return ErrNoBatchSize
+	}
+	info := &StepInfo{Step: t.Step, Epoch: t.Epoch, EpochStep: t.EpochStep}
+	defer func() {
+		if t.Events != nil {
+			t.Events.Emit(&StopEvent{Epoch: t.Epoch, Step: t.Step, Reason: err.Error()})
+		}
+		for _, h := range t.Hooks {
+			h.OnStop(info, err)
+		}
+	}()
+
+	tracker := newBudgetTracker(t.Budget)
+	for {
+		*info = StepInfo{Step: t.Step, Epoch: t.Epoch, EpochStep: t.EpochStep}
+		if t.EpochStep == 0 {
+			if err := tracker.checkEpoch(ctx); err != nil {
+				return err
+			}
+			if err := t.callHooks(Hook.BeforeEpoch, info); err != nil {
+				return err
+			}
+			if t.Rand != nil {
+				t.epochRandState = t.Rand.State()
+			}
+		} else if t.Rand != nil {
+			t.Rand.SetState(t.epochRandState)
+		}
+		epochStart := time.Now()
+		nextBatch := t.epochBatches()
+		for {
+			batch, err := nextBatch()
+			if err == io.EOF {
+				if t.EpochStep == 0 {
+					return ErrEmptyEpoch
+				}
+				break
+			} else if err != nil {
+				return err
+			}
+			if err := tracker.checkStep(ctx); err != nil {
+				if t.Batches != nil {
+					t.pendingBatch = batch
+				}
+				return err
+			}
+			info.Batch = batch
+			info.Gradient = nil
+			info.StepSize = t.Schedule.StepSize(t.Step, t.Epoch)
+			if err := t.callHooks(Hook.BeforeBatch, info); err != nil {
+				if t.Batches != nil {
+					t.pendingBatch = batch
+				}
+				return err
+			}
+			stepStart := time.Now()
+			info.Gradient = t.Gradienter.Gradient(info.Batch)
+			if info.Gradient != nil {
+				if t.LineSearch != nil {
+					info.Gradient, info.StepSize = t.lineSearch(info.Batch, info.Gradient,
+						info.StepSize)
+				} else {
+					info.Gradient.AddToVars(-info.StepSize)
+				}
+				t.Step++
+				tracker.steps++
+				if t.Events != nil {
+					t.Events.Emit(&StepEvent{
+						Step:         t.Step,
+						Epoch:        t.Epoch,
+						StepSize:     info.StepSize,
+						BatchSize:    info.Batch.Len(),
+						Duration:     time.Since(stepStart),
+						GradientNorm: math.Sqrt(gradientDot(info.Gradient, info.Gradient)),
+					})
+				}
+			}
+			t.EpochStep++
+			info.Step = t.Step
+			info.EpochStep = t.EpochStep
+			if err := t.callHooks(Hook.AfterBatch, info); err != nil {
+				return err
+			}
+		}
+		t.Epoch++
+		t.EpochStep = 0
+		tracker.epochs++
+		if t.Events != nil {
+			t.Events.Emit(&EpochEvent{
+				Epoch:    t.Epoch,
+				Step:     t.Step,
+				Duration: time.Since(epochStart),
+			})
+		}
+		info.Batch = nil
+		info.Gradient = nil
+		info.Epoch = t.Epoch
+		info.EpochStep = 0
+		if err := t.callHooks(Hook.AfterEpoch, info); err != nil {
+			return err
+		}
+	}
+}
+
+// epochBatches returns a function which produces the
+// remaining batches of the current epoch, followed by
+// io.EOF.
+func (t *Trainer) epochBatches() func() (SampleSet, error) {
+	if t.Batches != nil {
+		return func() (SampleSet, error) {
+			if t.pendingBatch != nil {
+				res := t.pendingBatch
+				t.pendingBatch = nil
+				return res, nil
+			}
+			return t.Batches.NextBatch()
+		}
+	}
+	samples := t.Samples.Copy()
+	ShuffleSampleSetRand(samples, t.Rand)
+	idx := t.EpochStep * t.BatchSize
+	return func() (SampleSet, error) {
+		if idx >= samples.Len() {
+			return nil, io.EOF
+		}
+		end := idx + t.BatchSize
+		if end > samples.Len() {
+			end = samples.Len()
+		}
+		res := samples.Subset(idx, end)
+		idx = end
+		return res, nil
+	}
+}
+
+// lineSearch moves the parameters along the negative
+// gradient and returns the step size that was used.
+//
+// Since the line search may compute other gradients, a
+// copy of the original gradient is returned as well.
+func (t *Trainer) lineSearch(batch SampleSet, grad autofunc.Gradient,
+	stepSize float64) (autofunc.Gradient, float64) {
+	dir := grad.Copy()
+	dir.Scale(-1)
+	stepSize, _ = t.LineSearch.LineSearch(batch, grad, dir, stepSize)
+	dir.Scale(-1)
+	return dir, stepSize
+}
+
+func (t *Trainer) callHooks(method func(Hook, *StepInfo) error, info *StepInfo) error {
+	for _, h := range t.Hooks {
+		if err := method(h, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
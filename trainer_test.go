@@ -0,0 +1,151 @@
+package sgd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// logHook records the hook calls it receives.
+type logHook struct {
+	Log []string
+}
+
+func (l *logHook) BeforeEpoch(info *StepInfo) error {
+	l.add("BeforeEpoch", info)
+	return nil
+}
+
+func (l *logHook) BeforeBatch(info *StepInfo) error {
+	l.add("BeforeBatch", info)
+	return nil
+}
+
+func (l *logHook) AfterBatch(info *StepInfo) error {
+	l.add("AfterBatch", info)
+	return nil
+}
+
+func (l *logHook) AfterEpoch(info *StepInfo) error {
+	l.add("AfterEpoch", info)
+	return nil
+}
+
+func (l *logHook) OnStop(info *StepInfo, reason error) {
+	l.add("OnStop("+reason.Error()+")", info)
+}
+
+func (l *logHook) add(name string, info *StepInfo) {
+	l.Log = append(l.Log, fmt.Sprintf("%s %d/%d/%d", name, info.Epoch, info.Step,
+		info.EpochStep))
+}
+
+func TestTrainerHookOrder(t *testing.T) {
+	hook := &logHook{}
+	trainer := &Trainer{
+		Gradienter: zeroTestGradienter{{Vector: []float64{1}}},
+		Samples:    SliceSampleSet{1.0, 2.0, 3.0},
+		BatchSize:  2,
+		Schedule:   ConstantSchedule(0.1),
+		Budget:     Budget{Epochs: 2},
+		Hooks:      []Hook{hook},
+	}
+	if err := trainer.Run(context.Background()); err != ErrEpochLimit {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"BeforeEpoch 0/0/0",
+		"BeforeBatch 0/0/0",
+		"AfterBatch 0/1/1",
+		"BeforeBatch 0/1/1",
+		"AfterBatch 0/2/2",
+		"AfterEpoch 1/2/0",
+		"BeforeEpoch 1/2/0",
+		"BeforeBatch 1/2/0",
+		"AfterBatch 1/3/1",
+		"BeforeBatch 1/3/1",
+		"AfterBatch 1/4/2",
+		"AfterEpoch 2/4/0",
+		"OnStop(" + ErrEpochLimit.Error() + ") 2/4/0",
+	}
+	checkHookLog(t, hook.Log, expected)
+}
+
+func TestTrainerStopped(t *testing.T) {
+	hook := &logHook{}
+	trainer := &Trainer{
+		Gradienter: zeroTestGradienter{{Vector: []float64{1}}},
+		Samples:    SliceSampleSet{1.0, 2.0, 3.0},
+		BatchSize:  1,
+		Schedule:   ConstantSchedule(0.1),
+		Hooks: []Hook{
+			&HookFuncs{
+				AfterBatchFunc: func(info *StepInfo) error {
+					if info.Step == 2 {
+						return ErrStopped
+					}
+					return nil
+				},
+			},
+			hook,
+		},
+	}
+	if err := trainer.Run(context.Background()); err != ErrStopped {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"BeforeEpoch 0/0/0",
+		"BeforeBatch 0/0/0",
+		"AfterBatch 0/1/1",
+		"BeforeBatch 0/1/1",
+		"OnStop(" + ErrStopped.Error() + ") 0/2/2",
+	}
+	checkHookLog(t, hook.Log, expected)
+
+	// Resuming finishes the interrupted epoch.
+	hook.Log = nil
+	trainer.Hooks = trainer.Hooks[1:]
+	trainer.Budget = Budget{Epochs: 1}
+	if err := trainer.Run(context.Background()); err != ErrEpochLimit {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []string{
+		"BeforeBatch 0/2/2",
+		"AfterBatch 0/3/3",
+		"AfterEpoch 1/3/0",
+		"OnStop(" + ErrEpochLimit.Error() + ") 1/3/0",
+	}
+	checkHookLog(t, hook.Log, expected)
+}
+
+func TestTrainerConfigErrors(t *testing.T) {
+	hook := &logHook{}
+	trainer := &Trainer{
+		Gradienter: zeroTestGradienter{{Vector: []float64{1}}},
+		Samples:    SliceSampleSet{1.0, 2.0, 3.0},
+		BatchSize:  1,
+		Hooks:      []Hook{hook},
+	}
+	if err := trainer.Run(context.Background()); err != ErrNoSchedule {
+		t.Errorf("expected ErrNoSchedule but got %v", err)
+	}
+	trainer.Schedule = ConstantSchedule(0.1)
+	trainer.BatchSize = 0
+	if err := trainer.Run(context.Background()); err != ErrNoBatchSize {
+		t.Errorf("expected ErrNoBatchSize but got %v", err)
+	}
+	if len(hook.Log) != 0 {
+		t.Errorf("unexpected hook calls: %v", hook.Log)
+	}
+}
+
+func checkHookLog(t *testing.T, actual, expected []string) {
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d calls but got %d: %v", len(expected), len(actual), actual)
+	}
+	for i, x := range expected {
+		if actual[i] != x {
+			t.Errorf("call %d: expected %q but got %q", i, x, actual[i])
+		}
+	}
+}
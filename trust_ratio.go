@@ -0,0 +1,155 @@
+package sgd
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+const larsDefaultTrustCoefficient = 0.001
+
+// LARS implements layer-wise adaptive rate scaling, as
+// described in https://arxiv.org/abs/1708.03888.
+//
+// Each variable's gradient (plus weight decay) is scaled
+// by a trust ratio proportional to the norm of the
+// variable divided by the norm of its gradient, so that
+// the size of every variable's update is proportional to
+// the size of the variable itself.
+// The scaled gradients are then fed through the embedded
+// Momentum, so LARS supports the same dampening and
+// Nesterov options.
+// If Momentum.Momentum is 0, no momentum is used.
+//
+// The parameters are read from the variables which key
+// the gradient, so LARS can be used anywhere a
+// Transformer can, including inside an
+// asyncsgd.TransformerUpdater, which owns the parameters
+// during each update.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+// LARS saves and loads its state like Momentum.
+type LARS struct {
+	Momentum
+
+	// WeightDecay is the coefficient of an L2 penalty
+	// which is added to the gradient before it is scaled.
+	WeightDecay float64
+
+	// TrustCoefficient scales the trust ratio.
+	// If it is 0, a default of 0.001 is used.
+	TrustCoefficient float64
+
+	// MaxTrustRatio, if non-zero, is the largest allowed
+	// trust ratio.
+	MaxTrustRatio float64
+
+	// Exclude contains variables which are updated
+	// without weight decay or trust ratios, such as
+	// biases and normalization parameters.
+	Exclude map[*autofunc.Variable]bool
+}
+
+func (l *LARS) Gradient(s SampleSet) autofunc.Gradient {
+	return l.Transform(l.Gradienter.Gradient(s))
+}
+
+func (l *LARS) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	coeff := defaultFloat(l.TrustCoefficient, larsDefaultTrustCoefficient)
+	for variable, vec := range grad {
+		if l.Exclude[variable] {
+			continue
+		}
+		paramNorm := vectorNorm(variable.Vector)
+		gradNorm := vectorNorm(vec)
+		ratio := 1.0
+		if paramNorm != 0 && gradNorm != 0 {
+			ratio = coeff * paramNorm / (gradNorm + l.WeightDecay*paramNorm)
+			if l.MaxTrustRatio != 0 {
+				ratio = math.Min(ratio, l.MaxTrustRatio)
+			}
+		}
+		for i, x := range variable.Vector {
+			vec[i] = ratio * (vec[i] + l.WeightDecay*x)
+		}
+	}
+	if l.Momentum.Momentum == 0 {
+		return grad
+	}
+	return l.Momentum.Transform(grad)
+}
+
+// LAMB implements the layer-wise adaptive optimizer
+// described in https://arxiv.org/abs/1904.00962.
+//
+// LAMB computes an update like AdamW, and then rescales
+// each variable's update so that its norm matches the
+// norm of the variable.
+// This keeps the relative change of every variable
+// roughly the same, even for very large batches.
+//
+// Like LARS, LAMB reads the parameters from the variables
+// which key the gradient, so it can be used inside an
+// asyncsgd.TransformerUpdater.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+// LAMB saves and loads its state like Adam.
+type LAMB struct {
+	Adam
+
+	// WeightDecay is the decoupled weight decay rate,
+	// as in AdamW.
+	WeightDecay float64
+
+	// MaxTrustRatio, if non-zero, is the largest allowed
+	// trust ratio.
+	MaxTrustRatio float64
+
+	// Exclude contains variables which are updated
+	// without weight decay or trust ratios (i.e. with
+	// plain Adam), such as biases and normalization
+	// parameters.
+	Exclude map[*autofunc.Variable]bool
+}
+
+func (l *LAMB) Gradient(s SampleSet) autofunc.Gradient {
+	return l.Transform(l.Gradienter.Gradient(s))
+}
+
+func (l *LAMB) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if grad == nil {
+		return nil
+	}
+	grad = l.Adam.Transform(grad)
+	for variable, vec := range grad {
+		if l.Exclude[variable] {
+			continue
+		}
+		for i, x := range variable.Vector {
+			vec[i] += l.WeightDecay * x
+		}
+		paramNorm := vectorNorm(variable.Vector)
+		updateNorm := vectorNorm(vec)
+		if paramNorm == 0 || updateNorm == 0 {
+			continue
+		}
+		ratio := paramNorm / updateNorm
+		if l.MaxTrustRatio != 0 {
+			ratio = math.Min(ratio, l.MaxTrustRatio)
+		}
+		vec.Scale(ratio)
+	}
+	return grad
+}
+
+func vectorNorm(v linalg.Vector) float64 {
+	return math.Sqrt(v.Dot(v))
+}
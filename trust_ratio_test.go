@@ -0,0 +1,102 @@
+package sgd
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+type constTestGradienter autofunc.Gradient
+
+func (c constTestGradienter) Gradient(s SampleSet) autofunc.Gradient {
+	return autofunc.Gradient(c).Copy()
+}
+
+func TestLARS(t *testing.T) {
+	weights := &autofunc.Variable{Vector: []float64{3, 4}}
+	biases := &autofunc.Variable{Vector: []float64{1}}
+	l := &LARS{
+		Momentum: Momentum{
+			Gradienter: constTestGradienter{
+				weights: {0, 10},
+				biases:  {2},
+			},
+		},
+		TrustCoefficient: 0.1,
+		Exclude:          map[*autofunc.Variable]bool{biases: true},
+	}
+	grad := l.Gradient(nil)
+
+	// The trust ratio is 0.1 * 5 / 10.
+	expected := map[*autofunc.Variable][]float64{weights: {0, 0.5}, biases: {2}}
+	for variable, vec := range expected {
+		for i, x := range vec {
+			if actual := grad[variable][i]; math.Abs(actual-x) > 1e-8 {
+				t.Errorf("expected %f but got %f", x, actual)
+			}
+		}
+	}
+}
+
+func TestLAMB(t *testing.T) {
+	weights := &autofunc.Variable{Vector: []float64{3, 4}}
+	biases := &autofunc.Variable{Vector: []float64{1}}
+	l := &LAMB{
+		Adam: Adam{
+			Gradienter: constTestGradienter{
+				weights: {1e-3, -1e3},
+				biases:  {2},
+			},
+		},
+		WeightDecay: 0.01,
+		Exclude:     map[*autofunc.Variable]bool{biases: true},
+	}
+	for i := 0; i < 3; i++ {
+		grad := l.Gradient(nil)
+		if norm := vectorNorm(grad[weights]); math.Abs(norm-5) > 1e-8 {
+			t.Errorf("expected update norm 5 but got %f", norm)
+		}
+		if x := grad[biases][0]; math.Abs(x-1) > 1e-3 {
+			t.Errorf("expected Adam update 1 but got %f", x)
+		}
+	}
+}
+
+func TestTrustRatioState(t *testing.T) {
+	newOptimizers := func(g Gradienter) map[string]adamVariant {
+		return map[string]adamVariant{
+			"LARS": &LARS{
+				Momentum:         Momentum{Gradienter: g, Momentum: 0.9},
+				WeightDecay:      0.01,
+				TrustCoefficient: 0.1,
+			},
+			"LAMB": &LAMB{Adam: Adam{Gradienter: g}, WeightDecay: 0.01},
+		}
+	}
+	for name := range newOptimizers(nil) {
+		q1 := newQuadraticTestGradienter()
+		o1 := newOptimizers(q1)[name]
+		runQuadraticTest(q1, o1, 5, 0.01)
+
+		var buf bytes.Buffer
+		if err := o1.SaveState(&buf, q1.Parameters()); err != nil {
+			t.Fatal(err)
+		}
+		q2 := newQuadraticTestGradienter()
+		copy(q2.Var.Vector, q1.Var.Vector)
+		o2 := newOptimizers(q2)[name]
+		if err := o2.LoadState(&buf, q2.Parameters()); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		runQuadraticTest(q1, o1, 5, 0.01)
+		runQuadraticTest(q2, o2, 5, 0.01)
+		for i, x := range q1.Var.Vector {
+			if q2.Var.Vector[i] != x {
+				t.Errorf("%s: entry %d: expected %f but got %f", name, i, x, q2.Var.Vector[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package sgd
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// SVRG is a Gradienter which implements stochastic
+// variance reduced gradients, as described in
+// https://papers.nips.cc/paper/4937-accelerating-stochastic-gradient-descent-using-predictive-variance-reduction.pdf.
+//
+// SVRG periodically takes a snapshot of the parameters
+// and computes the full gradient over Samples at the
+// snapshot.
+// For each mini-batch, it returns the mini-batch gradient
+// minus the mini-batch gradient at the snapshot, plus the
+// full gradient (scaled to the size of the mini-batch).
+// This has the same expectation as the mini-batch
+// gradient, but its variance vanishes as the parameters
+// and the snapshot approach an optimum, so SGD can
+// converge with a constant step size.
+//
+// Every mini-batch costs two gradient computations, and
+// every snapshot costs a pass over Samples.
+//
+// The wrapped Gradienter must never return nil, so it
+// cannot be an Accumulator.
+type SVRG struct {
+	Gradienter Gradienter
+	Learner    Learner
+
+	// Samples is the full sample set whose gradient is
+	// computed at every snapshot.
+	Samples SampleSet
+
+	// Interval is the number of Gradient calls between
+	// snapshots.
+	// If it is 0, a snapshot is taken once per pass over
+	// Samples, based on the size of the mini-batch which
+	// triggered the previous snapshot.
+	Interval int
+
+	snapshot []linalg.Vector
+	fullGrad autofunc.Gradient
+	interval int
+	calls    int
+}
+
+// Gradient computes the variance-reduced gradient for a
+// mini-batch, taking a new snapshot first if necessary.
+func (s *SVRG) Gradient(batch SampleSet) autofunc.Gradient {
+	params := s.Learner.Parameters()
+	scale := float64(batch.Len()) / float64(s.Samples.Len())
+	if s.snapshot == nil || s.calls >= s.interval {
+		s.takeSnapshot(params, batch.Len())
+		res := s.fullGrad.Copy()
+		res.Scale(scale)
+		s.calls++
+		return res
+	}
+	s.calls++
+
+	res := mustGradient(s.Gradienter, batch).Copy()
+	current := snapshotParams(params)
+	restoreParams(params, s.snapshot)
+	res.Add(scaledGradient(mustGradient(s.Gradienter, batch), -1))
+	restoreParams(params, current)
+	res.Add(scaledGradient(s.fullGrad, scale))
+	return res
+}
+
+func (s *SVRG) takeSnapshot(params []*autofunc.Variable, batchSize int) {
+	s.snapshot = snapshotParams(params)
+	s.fullGrad = mustGradient(s.Gradienter, s.Samples).Copy()
+	s.calls = 0
+	s.interval = s.Interval
+	if s.interval == 0 {
+		s.interval = (s.Samples.Len() + batchSize - 1) / batchSize
+	}
+}
+
+// SAGA implements the SAGA incremental gradient method,
+// as described in https://arxiv.org/abs/1407.0202.
+//
+// SAGA stores the most recent gradient of every sample in
+// a table.
+// Each step picks a random sample and moves along its new
+// gradient, minus its gradient from the table, plus the
+// average of the table.
+// Like SVRG, this allows convergence with a constant step
+// size, but it never needs a full pass over the data
+// after the table is filled.
+//
+// The table holds one gradient per sample, each as large
+// as the full set of parameters, so it takes about
+// 8*Samples.Len()*(number of parameters) bytes of memory.
+// For example, a model with a million parameters and
+// 10,000 samples needs about 80GB.
+// Thus, SAGA is mainly suitable for small models, such as
+// linear models.
+//
+// Since samples are identified by their indices, Samples
+// must not be reordered while SAGA is in use.
+// The Gradienter must never return nil.
+type SAGA struct {
+	Gradienter Gradienter
+	Samples    SampleSet
+
+	// Rand, if non-nil, is used to choose samples.
+	// Otherwise, the global source from math/rand is used.
+	Rand *RandSource
+
+	table []autofunc.Gradient
+	sum   autofunc.Gradient
+}
+
+// Run performs SAGA steps until ctx is done or the budget
+// is exhausted.
+// Each step uses a single sample, and each epoch consists
+// of Samples.Len() steps.
+//
+// The first call to Run fills the gradient table by
+// computing the gradient of every sample, which does not
+// count towards the budget.
+// Later calls reuse the table.
+//
+// Like SGDContext, Run never returns a nil error.
+// If Samples is empty, Run returns ErrEmptyEpoch.
+func (s *SAGA) Run(ctx context.Context, stepSize float64, b Budget) error {
+	if s.Samples.Len() == 0 {
+		return ErrEmptyEpoch
+	}
+	if s.table == nil {
+		s.fillTable()
+	}
+	intn := rand.Intn
+	if s.Rand != nil {
+		intn = rand.New(s.Rand).Intn
+	}
+	tracker := newBudgetTracker(b)
+	n := s.Samples.Len()
+	for {
+		if err := tracker.checkEpoch(ctx); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := tracker.checkStep(ctx); err != nil {
+				return err
+			}
+			idx := intn(n)
+			s.step(idx, mustGradient(s.Gradienter, s.Samples.Subset(idx, idx+1)), stepSize)
+			tracker.steps++
+		}
+		tracker.epochs++
+	}
+}
+
+func (s *SAGA) fillTable() {
+	s.table = make([]autofunc.Gradient, s.Samples.Len())
+	for i := range s.table {
+		s.table[i] = mustGradient(s.Gradienter, s.Samples.Subset(i, i+1)).Copy()
+		if s.sum == nil {
+			s.sum = s.table[i].Copy()
+			continue
+		}
+		for variable, vec := range s.table[i] {
+			if sumVec, ok := s.sum[variable]; ok {
+				for j, x := range vec {
+					sumVec[j] += x
+				}
+			} else {
+				s.sum[variable] = vec.Copy()
+			}
+		}
+	}
+}
+
+// step updates the parameters and the table with a new
+// gradient for a sample.
+//
+// A variable which is missing from a gradient is treated
+// as having a zero gradient for that sample.
+func (s *SAGA) step(idx int, grad autofunc.Gradient, stepSize float64) {
+	n := float64(len(s.table))
+	old := s.table[idx]
+	for variable, vec := range grad {
+		if _, ok := old[variable]; !ok {
+			old[variable] = make(linalg.Vector, len(vec))
+		}
+		if _, ok := s.sum[variable]; !ok {
+			s.sum[variable] = make(linalg.Vector, len(vec))
+		}
+	}
+	for variable, sumVec := range s.sum {
+		vec := grad[variable]
+		oldVec := old[variable]
+		for i := range sumVec {
+			var x, oldX float64
+			if vec != nil {
+				x = vec[i]
+			}
+			if oldVec != nil {
+				oldX = oldVec[i]
+			}
+			diff := x - oldX
+			variable.Vector[i] -= stepSize * (diff + sumVec[i]/n)
+			sumVec[i] += diff
+			if oldVec != nil {
+				oldVec[i] = x
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sgd
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+func varianceReductionTestSamples() (SliceSampleSet, float64) {
+	var samples SliceSampleSet
+	var sum float64
+	for i := 0; i < 17; i++ {
+		x := float64(i%5) - 1.5
+		samples = append(samples, x)
+		sum += x
+	}
+	return samples, sum / float64(len(samples))
+}
+
+func TestSVRG(t *testing.T) {
+	samples, mean := varianceReductionTestSamples()
+	g := newTargetTestGradienter()
+	svrg := &SVRG{Gradienter: g, Learner: g, Samples: samples}
+	err := SGDContext(context.Background(), svrg, samples, 0.1, 1, Budget{Epochs: 30}, nil)
+	if err != ErrEpochLimit {
+		t.Fatal(err)
+	}
+	checkVarianceReduction(t, g, mean)
+}
+
+func TestSAGA(t *testing.T) {
+	samples, mean := varianceReductionTestSamples()
+	g := newTargetTestGradienter()
+	saga := &SAGA{Gradienter: g, Samples: samples, Rand: NewRandSource(1)}
+	if err := saga.Run(context.Background(), 0.1, Budget{Epochs: 30}); err != ErrEpochLimit {
+		t.Fatal(err)
+	}
+	checkVarianceReduction(t, g, mean)
+
+	if err := saga.Run(context.Background(), 0.1, Budget{Steps: 3}); err != ErrStepLimit {
+		t.Fatal(err)
+	}
+}
+
+func checkVarianceReduction(t *testing.T, g *targetTestGradienter, mean float64) {
+	for _, v := range g.Vars {
+		for i, x := range v.Vector {
+			if math.Abs(x-mean) > 1e-6 {
+				t.Errorf("component %d: expected %f but got %f", i, mean, x)
+			}
+		}
+	}
+}
+
+func TestSAGAEmpty(t *testing.T) {
+	saga := &SAGA{Gradienter: newTargetTestGradienter(), Samples: SliceSampleSet{}}
+	if err := saga.Run(context.Background(), 0.1, Budget{Steps: 3}); err != ErrEmptyEpoch {
+		t.Errorf("expected ErrEmptyEpoch but got %v", err)
+	}
+}
+
+func TestSAGAChangingVariables(t *testing.T) {
+	v1 := &autofunc.Variable{Vector: []float64{0}}
+	v2 := &autofunc.Variable{Vector: []float64{0}}
+	g := constTestGradienter{v1: {1}}
+	saga := &SAGA{Gradienter: g, Samples: SliceSampleSet{1.0}}
+	saga.fillTable()
+	saga.step(0, autofunc.Gradient{v2: []float64{2}}, 0.5)
+
+	// The sum starts as 1 for v1 and 0 for v2, and the
+	// step moves along the new gradient (0 and 2), minus
+	// the old gradient (1 and 0), plus the average.
+	if v1.Vector[0] != 0 || v2.Vector[0] != -1 {
+		t.Errorf("unexpected parameters %v and %v", v1.Vector, v2.Vector)
+	}
+	if saga.sum[v1][0] != 0 || saga.sum[v2][0] != 2 || saga.table[0][v2][0] != 2 {
+		t.Errorf("unexpected table %v with sum %v", saga.table, saga.sum)
+	}
+}